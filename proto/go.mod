@@ -0,0 +1,8 @@
+module proto
+
+go 1.23
+
+require (
+	google.golang.org/grpc v1.72.1
+	google.golang.org/protobuf v1.36.6
+)
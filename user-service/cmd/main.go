@@ -1,21 +1,30 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/grpc"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	userpb "proto/generated/ecommerce/user"
+	"strings"
+	"syscall"
+	"time"
 	"user-service/internal/config"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
 	"user-service/internal/delivery/grpc/middleware"
 	"user-service/internal/infrastructure/cache"
 	"user-service/internal/infrastructure/database"
 	"user-service/internal/infrastructure/email"
 	"user-service/internal/infrastructure/logger"
+	"user-service/internal/infrastructure/oidc"
 	"user-service/internal/infrastructure/repositories"
 	"user-service/internal/infrastructure/utils/jwt"
 	"user-service/internal/infrastructure/utils/security"
@@ -26,33 +35,156 @@ import (
 	"user-service/internal/usecases/validators"
 )
 
-func initRepositories() (repositories2.UserRepository, repositories2.OrderRepository, *mongo.Client, error) {
+// appRepositories holds the repositories of every collection the service
+// uses.
+type appRepositories struct {
+	users    repositories2.UserRepository
+	orders   repositories2.OrderRepository
+	products repositories2.ProductRepository
+	apiKeys  repositories2.APIKeyRepository
+	audit    repositories2.AuditRepository
+}
+
+func initRepositories(passwordHash security.PasswordHash) (*appRepositories, error) {
 
 	client, err := database.ConnectMongoClient()
 
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to connect to mongo client: %v", err)
+		return nil, fmt.Errorf("failed to connect to mongo client: %v", err)
 	}
 
 	userDB := client.Database("users")
 	orderDB := client.Database("orders")
+	inventoryDB := client.Database("inventory")
+
+	repos := &appRepositories{
+		users:    repositories.NewUserRepositoryMongo(userDB, passwordHash),
+		orders:   repositories.NewOrderRepositoryMongo(orderDB),
+		products: repositories.NewProductRepositoryMongo(inventoryDB),
+		apiKeys:  repositories.NewAPIKeyRepositoryMongo(userDB),
+		audit:    repositories.NewAuditRepositoryMongo(userDB),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := repos.users.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create user indexes: %v", err)
+	}
+	if err := repos.audit.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create audit indexes: %v", err)
+	}
+	if err := repos.apiKeys.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create API key indexes: %v", err)
+	}
+
+	currency := config.GetEnv("DEFAULT_CURRENCY", "USD")
+	if !models.IsValidCurrency(currency) {
+		return nil, fmt.Errorf("DEFAULT_CURRENCY %q is not an ISO 4217 code", currency)
+	}
+	migrated, err := repos.orders.MigrateMoney(ctx, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate order prices: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Migrated prices of %d orders to %s", migrated, currency)
+	}
+	migrated, err = repos.products.MigrateMoney(ctx, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate product prices: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Migrated prices of %d products to %s", migrated, currency)
+	}
+	migrated, err = repos.orders.MigrateStatuses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate order statuses: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Migrated %d completed orders to delivered", migrated)
+	}
+
+	return repos, nil
+}
+
+func initPasswordHash() (*security.VersionedHash, error) {
+	defaults := security.DefaultArgon2Params()
+	argon2Params := security.Argon2Params{
+		Memory:      uint32(config.GetEnvAsInt("ARGON2_MEMORY_KIB", int(defaults.Memory))),
+		Iterations:  uint32(config.GetEnvAsInt("ARGON2_ITERATIONS", int(defaults.Iterations))),
+		Parallelism: uint8(config.GetEnvAsInt("ARGON2_PARALLELISM", int(defaults.Parallelism))),
+		SaltLength:  defaults.SaltLength,
+		KeyLength:   defaults.KeyLength,
+	}
+
+	return security.NewVersionedHash(
+		config.GetEnv("PASSWORD_HASH_ALGORITHM", security.AlgorithmArgon2id),
+		argon2Params,
+		config.GetEnvAsInt("BCRYPT_COST", bcrypt.DefaultCost),
+	)
+}
+
+func initPasswordPolicy() validators.PasswordPolicy {
+	defaults := validators.DefaultPasswordPolicy()
+	return validators.PasswordPolicy{
+		MinLength:      config.GetEnvAsInt("PASSWORD_MIN_LENGTH", defaults.MinLength),
+		MaxLength:      config.GetEnvAsInt("PASSWORD_MAX_LENGTH", defaults.MaxLength),
+		RequireUpper:   config.GetEnvAsBool("PASSWORD_REQUIRE_UPPER", defaults.RequireUpper),
+		RequireLower:   config.GetEnvAsBool("PASSWORD_REQUIRE_LOWER", defaults.RequireLower),
+		RequireDigit:   config.GetEnvAsBool("PASSWORD_REQUIRE_DIGIT", defaults.RequireDigit),
+		RequireSymbol:  config.GetEnvAsBool("PASSWORD_REQUIRE_SYMBOL", defaults.RequireSymbol),
+		AllowedSymbols: config.GetEnv("PASSWORD_ALLOWED_SYMBOLS", defaults.AllowedSymbols),
+	}
+}
 
-	userRepo := repositories.NewUserRepositoryMongo(userDB, security.NewBcryptHash())
-	orderRepo := repositories.NewOrderRepositoryMongo(orderDB)
+// initOIDCProviders discovers the providers listed in OIDC_PROVIDERS. Each one
+// is configured with OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET and
+// _REDIRECT_URL. A provider that cannot be reached is skipped.
+func initOIDCProviders() []*oidc.Provider {
+	var providers []*oidc.Provider
+	for _, name := range strings.Split(config.GetEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
 
-	return userRepo, orderRepo, client, nil
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidc.NewProvider(ctx, oidc.ProviderConfig{
+			Name:         name,
+			Issuer:       config.GetEnv(prefix+"ISSUER", ""),
+			ClientID:     config.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: config.GetEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  config.GetEnv(prefix+"REDIRECT_URL", ""),
+		}, nil)
+		cancel()
+		if err != nil {
+			log.Printf("Skipping OIDC provider %s: %v", name, err)
+			continue
+		}
+
+		log.Printf("OIDC provider %s enabled", name)
+		providers = append(providers, provider)
+	}
+	return providers
 }
 
-func startMetricsServer() {
+func startMetricsServer(keySet *jwt.KeySet) {
 	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/.well-known/jwks.json", keySet.JWKSHandler())
 	log.Println("Prometheus metrics available on :8081/metrics")
+	log.Println("JWKS available on :8081/.well-known/jwks.json")
 	if err := http.ListenAndServe(":8081", nil); err != nil {
 		log.Fatalf("Failed to start metrics server: %v", err)
 	}
 }
 
 func main() {
-	userRepo, orderRepo, client, err := initRepositories()
+	passwordHash, err := initPasswordHash()
+	if err != nil {
+		log.Fatalf("Failed to initialize password hashing: %v", err)
+	}
+
+	repos, err := initRepositories(passwordHash)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -60,8 +192,13 @@ func main() {
 	secretKey := config.GetEnv("JWT_SECRET_KEY", "")
 	stdLogger := &logger.StdLogger{}
 
-	userValidator := validators.NewUserValidator()
-	passwordHash := security.NewBcryptHash()
+	breachedPasswords, err := validators.LoadBreachedPasswords(config.GetEnv("BREACHED_PASSWORDS_FILE", ""))
+	if err != nil {
+		log.Fatalf("Failed to load breached password list: %v", err)
+	}
+	log.Printf("Loaded %d breached passwords", len(breachedPasswords))
+
+	userValidator := validators.NewUserValidator(initPasswordPolicy(), breachedPasswords)
 	uuidGen := uuid.NewUUIDService()
 
 	redisAddr := config.GetEnv("REDIS_ADDR", "")
@@ -69,25 +206,65 @@ func main() {
 	redisDB := 0
 	redisClient := cache.NewRedisCache(redisAddr, redisPassword, redisDB)
 
-	var jwtService jwt.JWTService = jwt.NewJWTService(secretKey, redisClient)
+	// Without a shared key directory every restart and replica would sign with
+	// its own fresh key, invalidating the tokens issued by the others.
+	keysDir := config.GetEnv("JWT_KEYS_DIR", "")
+	if keysDir == "" {
+		log.Fatal("JWT_KEYS_DIR must be set to the directory holding the JWT signing keys")
+	}
+	keySet, err := jwt.NewKeySet(config.GetEnv("JWT_SIGNING_ALG", jwt.AlgorithmRS256), keysDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize JWT signing keys: %v", err)
+	}
+	go keySet.StartRotation(config.GetEnvAsDuration("JWT_KEY_ROTATION_INTERVAL", 7*24*time.Hour))
+
+	var jwtService jwt.JWTService = jwt.NewJWTService(keySet, secretKey, redisClient)
+
+	verificationPolicy := auth.EmailVerificationPolicy(config.GetEnv("EMAIL_VERIFICATION_POLICY", string(auth.EmailVerificationForOrders)))
+	switch verificationPolicy {
+	case auth.EmailVerificationOptional, auth.EmailVerificationForLogin, auth.EmailVerificationForOrders:
+	default:
+		log.Fatalf("Unknown EMAIL_VERIFICATION_POLICY %q", verificationPolicy)
+	}
+
+	apiKeyService := services.NewAPIKeyService(repos.apiKeys, uuidGen, stdLogger, middleware.AuthorizeScope)
 
 	grpcServer := grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
 			grpc_prometheus.UnaryServerInterceptor,
-			middleware.JWTInterceptor(jwtService),
+			middleware.JWTInterceptor(jwtService, apiKeyService, verificationPolicy),
+			middleware.ErrorInterceptor(),
 		),
 		grpc.ChainStreamInterceptor(
 			grpc_prometheus.StreamServerInterceptor,
+			middleware.JWTStreamInterceptor(jwtService, apiKeyService, verificationPolicy),
+			middleware.ErrorStreamInterceptor(),
 		),
 	)
 
 	emailService := email.NewSMTPEmailService()
 
-	userService := services.NewUserService(userRepo, userValidator, passwordHash, jwtService, uuidGen, client, orderRepo, redisClient, stdLogger, emailService)
-	userServer := grpc2.NewUserGrpcServer(userService, jwtService, stdLogger, redisClient)
+	// ctx is cancelled on SIGINT or SIGTERM, which stops the background jobs
+	// and shuts the server down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	auditLog := services.NewAuditLog(repos.audit, stdLogger, config.GetEnvAsInt("AUDIT_QUEUE_SIZE", 1000))
+
+	deletionGracePeriod := config.GetEnvAsDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour)
+	userService := services.NewUserService(repos.users, userValidator, passwordHash, jwtService, uuidGen, repos.orders, redisClient, stdLogger, emailService, verificationPolicy, deletionGracePeriod, auditLog)
+	go userService.StartAccountPurger(ctx, config.GetEnvAsDuration("ACCOUNT_PURGE_INTERVAL", time.Hour))
+	oidcLoginService := services.NewOIDCLoginService(initOIDCProviders(), repos.users, redisClient, uuidGen, stdLogger, auditLog)
+	userServer := grpc2.NewUserGrpcServer(userService, apiKeyService, oidcLoginService, auditLog, jwtService, stdLogger, redisClient)
 	userpb.RegisterUserServiceServer(grpcServer, userServer)
 
-	go startMetricsServer()
+	// unpaid orders give their reserved stock back after ORDER_RESERVATION_TTL
+	productService := services.NewProductService(repos.products, stdLogger, redisClient)
+	orderService := services.NewOrderService(repos.orders, services.NewPriceCalculator(), uuidGen, productService, redisClient, stdLogger,
+		config.GetEnvAsDuration("ORDER_RESERVATION_TTL", 30*time.Minute))
+	go orderService.StartReservationReaper(ctx, config.GetEnvAsDuration("ORDER_RESERVATION_REAP_INTERVAL", time.Minute))
+
+	go startMetricsServer(keySet)
 
 	grpc_prometheus.Register(grpcServer)
 
@@ -96,7 +273,20 @@ func main() {
 		log.Fatalf("Failed to listen on port 50051: %v", err)
 	}
 	log.Println("User Service is running on port :50051")
+
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		log.Println("Shutting down User Service")
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
 	if err := grpcServer.Serve(listener); err != nil {
 		log.Fatalf("Failed to serve gRPC: %v", err)
 	}
+
+	// the requests in flight have finished, write the events they recorded
+	<-stopped
+	auditLog.Close()
 }
@@ -4,6 +4,8 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 func LoadConfig() {
@@ -19,3 +21,45 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func GetEnvAsInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s, using default %d", key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+func GetEnvAsBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s, using default %t", key, defaultValue)
+		return defaultValue
+	}
+	return flag
+}
+
+func GetEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s, using default %s", key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"user-service/internal/core/models"
+	"user-service/internal/errors"
+)
+
+// EmailVerificationPolicy decides what an account with an unverified email
+// address is allowed to do.
+type EmailVerificationPolicy string
+
+const (
+	EmailVerificationOptional  EmailVerificationPolicy = "none"
+	EmailVerificationForLogin  EmailVerificationPolicy = "login"
+	EmailVerificationForOrders EmailVerificationPolicy = "order"
+)
+
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request: either a user holding a
+// JWT or a service using an API key. API keys are not bound to a user and are
+// limited to the RPCs listed in Scopes instead of roles.
+type Principal struct {
+	Type          string
+	UserID        string
+	Roles         []string
+	EmailVerified bool
+	SessionID     string
+	APIKeyID      string
+	Scopes        []string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return models.HasRole(p.Roles, role)
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(models.RoleAdmin)
+}
+
+func (p *Principal) IsAPIKey() bool {
+	return p.Type == PrincipalAPIKey
+}
+
+func (p *Principal) HasScope(method string) bool {
+	for _, scope := range p.Scopes {
+		if scope == method {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// ResolveUserID returns the user whose resources the caller acts on. An empty
+// requestedID means the caller itself; another user's ID is only accepted for
+// admins and API keys. API keys have no user of their own and must always name
+// one.
+func ResolveUserID(ctx context.Context, requestedID string) (string, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return "", errors.ErrUnauthenticated
+	}
+
+	if principal.IsAPIKey() {
+		if requestedID == "" {
+			return "", errors.ErrUserIDRequired
+		}
+		return requestedID, nil
+	}
+
+	if requestedID == "" || requestedID == principal.UserID {
+		return principal.UserID, nil
+	}
+
+	if principal.IsAdmin() {
+		return requestedID, nil
+	}
+
+	return "", errors.ErrPermissionDenied
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+const (
+	AuditLogin               = "login"
+	AuditLogout              = "logout"
+	AuditLogoutAllSessions   = "logout_all_sessions"
+	AuditSessionRevoked      = "session_revoked"
+	AuditPasswordChanged     = "password_changed"
+	AuditPasswordReset       = "password_reset"
+	AuditTwoFactorEnabled    = "two_factor_enabled"
+	AuditTwoFactorDisabled   = "two_factor_disabled"
+	AuditAccountDeleted      = "account_deleted"
+	AuditAccountRestored     = "account_restored"
+	AuditAccountAnonymized   = "account_anonymized"
+	AuditAccountDisabled     = "account_disabled"
+	AuditAccountEnabled      = "account_enabled"
+	AuditPasswordResetForced = "password_reset_forced"
+	AuditRolesChanged        = "roles_changed"
+	AuditIdentityLinked      = "identity_linked"
+	AuditRefreshTokenReused  = "refresh_token_reused"
+	AuditAccountLocked       = "account_locked"
+	AuditAccountUnlocked     = "account_unlocked"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+const (
+	AuditActorUser   = "user"
+	AuditActorAPIKey = "api_key"
+	AuditActorSystem = "system"
+)
+
+// AuditEvent is an append-only record of a security relevant action. TargetID
+// is the user the action concerns; the actor can be that user, an admin, an
+// API key or the service itself.
+type AuditEvent struct {
+	ID         string            `json:"id" bson:"_id,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at" bson:"occurred_at"`
+	ActorType  string            `json:"actor_type" bson:"actor_type"`
+	ActorID    string            `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	Action     string            `json:"action" bson:"action"`
+	TargetID   string            `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	IP         string            `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	Outcome    string            `json:"outcome" bson:"outcome"`
+	Reason     string            `json:"reason,omitempty" bson:"reason,omitempty"`
+	Details    map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+}
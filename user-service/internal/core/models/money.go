@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"user-service/internal/errors"
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// minorUnitDigits lists the ISO 4217 currencies whose minor unit is not a
+// hundredth of the major unit.
+var minorUnitDigits = map[string]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "LYD": 3, "OMR": 3, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// Money is an amount in the minor unit of an ISO 4217 currency, such as cents
+// for USD. Integer amounts keep totals exact.
+type Money struct {
+	Amount   int64  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// IsValidCurrency reports whether code looks like an ISO 4217 currency code.
+func IsValidCurrency(code string) bool {
+	return currencyCode.MatchString(code)
+}
+
+// MinorUnitDigits returns the number of decimal digits of the minor unit of
+// currency.
+func MinorUnitDigits(currency string) int {
+	if digits, ok := minorUnitDigits[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+func minorUnitFactor(currency string) int64 {
+	factor := int64(1)
+	for i := 0; i < MinorUnitDigits(currency); i++ {
+		factor *= 10
+	}
+	return factor
+}
+
+// MoneyFromFloat converts an amount in major units, as carried by the proto
+// messages and older documents, rounding to the nearest minor unit.
+func MoneyFromFloat(value float64, currency string) Money {
+	return Money{
+		Amount:   int64(math.Round(value * float64(minorUnitFactor(currency)))),
+		Currency: currency,
+	}
+}
+
+// Float returns the amount in major units. It is meant for display and proto
+// mapping only, never for arithmetic.
+func (m Money) Float() float64 {
+	return float64(m.Amount) / float64(minorUnitFactor(m.Currency))
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Add returns the sum of m and other, which must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", errors.ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Multiply(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// String formats the amount in major units followed by the currency code,
+// for example "12.50 USD".
+func (m Money) String() string {
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := MinorUnitDigits(m.Currency)
+	factor := minorUnitFactor(m.Currency)
+	if digits == 0 {
+		return fmt.Sprintf("%s%d %s", sign, amount, m.Currency)
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/factor, digits, amount%factor, m.Currency)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	stderrors "errors"
+	"testing"
+	"user-service/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("Converts floats to minor units without drift", func(t *testing.T) {
+		assert.Equal(t, Money{Amount: 1999, Currency: "USD"}, MoneyFromFloat(19.99, "USD"))
+		assert.Equal(t, Money{Amount: 30, Currency: "USD"}, MoneyFromFloat(0.1+0.2, "USD"))
+		assert.Equal(t, Money{Amount: 500, Currency: "JPY"}, MoneyFromFloat(500, "JPY"))
+		assert.Equal(t, Money{Amount: 1250, Currency: "KWD"}, MoneyFromFloat(1.25, "KWD"))
+	})
+
+	t.Run("Adds and multiplies exactly", func(t *testing.T) {
+		total := Money{Currency: "EUR"}
+		for i := 0; i < 10; i++ {
+			var err error
+			total, err = total.Add(MoneyFromFloat(0.1, "EUR"))
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, Money{Amount: 100, Currency: "EUR"}, total)
+		assert.Equal(t, Money{Amount: 2997, Currency: "EUR"}, MoneyFromFloat(9.99, "EUR").Multiply(3))
+	})
+
+	t.Run("Refuses to mix currencies", func(t *testing.T) {
+		_, err := MoneyFromFloat(1, "USD").Add(MoneyFromFloat(1, "EUR"))
+		assert.True(t, stderrors.Is(err, errors.ErrCurrencyMismatch))
+	})
+
+	t.Run("Formats in major units", func(t *testing.T) {
+		assert.Equal(t, "12.05 USD", Money{Amount: 1205, Currency: "USD"}.String())
+		assert.Equal(t, "-0.50 EUR", Money{Amount: -50, Currency: "EUR"}.String())
+		assert.Equal(t, "500 JPY", Money{Amount: 500, Currency: "JPY"}.String())
+		assert.Equal(t, "1.250 KWD", Money{Amount: 1250, Currency: "KWD"}.String())
+	})
+
+	t.Run("Validates currency codes", func(t *testing.T) {
+		assert.True(t, IsValidCurrency("USD"))
+		assert.False(t, IsValidCurrency("usd"))
+		assert.False(t, IsValidCurrency(""))
+	})
+}
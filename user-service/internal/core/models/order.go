@@ -2,19 +2,99 @@ package models
 
 import "time"
 
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusPaid       = "paid"
+	OrderStatusFulfilling = "fulfilling"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusRefunded   = "refunded"
+)
+
+// LegacyOrderStatusCompleted is what older versions called a delivered order.
+// It is not a valid status anymore; stored orders are migrated to
+// OrderStatusDelivered at startup.
+const LegacyOrderStatusCompleted = "completed"
+
+// OrderActorSystem is the actor of status changes made by the service itself.
+const OrderActorSystem = "system"
+
+// orderTransitions lists, for every order status, the statuses an order can
+// move to from it. Statuses without an entry are final.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:    {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:       {OrderStatusFulfilling, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusFulfilling: {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusShipped:    {OrderStatusDelivered, OrderStatusRefunded},
+	OrderStatusDelivered:  {OrderStatusRefunded},
+	OrderStatusCancelled:  nil,
+	OrderStatusRefunded:   nil,
+}
+
 type Order struct {
 	ID         string      `json:"id" bson:"_id,omitempty"`
 	UserID     string      `json:"user_id" bson:"user_id"`
-	OrderID    string `json:"order_id" bson:"order_id"`
+	OrderID    string      `json:"order_id" bson:"order_id"`
 	Status     string      `json:"status" bson:"status"`
-	TotalPrice float64     `json:"total_price" bson:"total_price"`
+	TotalPrice Money       `json:"total_price" bson:"total_price"`
 	CreatedAt  time.Time   `json:"created_at" bson:"created_at"`
 	UpdatedAt  time.Time   `json:"updated_at" bson:"updated_at"`
 	Items      []OrderItem `json:"items" bson:"items"`
+	// StatusHistory records every status the order went through, oldest first,
+	// starting with its creation.
+	StatusHistory []OrderStatusChange `json:"status_history" bson:"status_history"`
+	// ReservationExpiresAt is when a pending order that is still unpaid gets
+	// cancelled and its reserved stock returned.
+	ReservationExpiresAt *time.Time `json:"reservation_expires_at,omitempty" bson:"reservation_expires_at,omitempty"`
 }
 
+// OrderItem is one line of an order. PricePerUnit and ProductName are copied
+// from the catalog when the order is created, so later catalog changes do not
+// affect existing orders.
 type OrderItem struct {
-	ProductID    string  `json:"product_id" bson:"product_id"`
-	Quantity     int     `json:"quantity" bson:"quantity"`
-	PricePerUnit float64 `json:"price_per_unit" bson:"price_per_unit"`
-}
\ No newline at end of file
+	ProductID    string `json:"product_id" bson:"product_id"`
+	ProductName  string `json:"product_name" bson:"product_name"`
+	Quantity     int    `json:"quantity" bson:"quantity"`
+	PricePerUnit Money  `json:"price_per_unit" bson:"price_per_unit"`
+}
+
+// OrderStatusChange is one entry of an order's status history. Actor is the
+// user or API key that made the change, or OrderActorSystem for automatic
+// ones.
+type OrderStatusChange struct {
+	Status    string    `json:"status" bson:"status"`
+	ChangedAt time.Time `json:"changed_at" bson:"changed_at"`
+	Actor     string    `json:"actor" bson:"actor"`
+}
+
+// HoldsStock reports whether stock is set aside for the order. Orders reserve
+// their stock when they are created and keep it until they ship; orders
+// created before reservations existed never held any.
+func (o *Order) HoldsStock() bool {
+	if o.ReservationExpiresAt == nil {
+		return false
+	}
+	switch o.Status {
+	case OrderStatusPending, OrderStatusPaid, OrderStatusFulfilling:
+		return true
+	}
+	return false
+}
+
+// IsValidOrderStatus reports whether status is a known order status.
+func IsValidOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// CanTransitionOrder reports whether an order in status from may move to
+// status to.
+func CanTransitionOrder(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
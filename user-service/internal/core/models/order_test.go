@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionOrder(t *testing.T) {
+	t.Run("Follows the order lifecycle", func(t *testing.T) {
+		lifecycle := []string{OrderStatusPending, OrderStatusPaid, OrderStatusFulfilling, OrderStatusShipped, OrderStatusDelivered, OrderStatusRefunded}
+		for i := 1; i < len(lifecycle); i++ {
+			assert.True(t, CanTransitionOrder(lifecycle[i-1], lifecycle[i]), "%s -> %s", lifecycle[i-1], lifecycle[i])
+		}
+	})
+
+	t.Run("Allows cancelling before shipping", func(t *testing.T) {
+		assert.True(t, CanTransitionOrder(OrderStatusPending, OrderStatusCancelled))
+		assert.True(t, CanTransitionOrder(OrderStatusFulfilling, OrderStatusCancelled))
+		assert.False(t, CanTransitionOrder(OrderStatusShipped, OrderStatusCancelled))
+	})
+
+	t.Run("Final statuses cannot change", func(t *testing.T) {
+		for status := range orderTransitions {
+			assert.False(t, CanTransitionOrder(OrderStatusCancelled, status), "cancelled -> %s", status)
+			assert.False(t, CanTransitionOrder(OrderStatusRefunded, status), "refunded -> %s", status)
+		}
+	})
+
+	t.Run("Rejects skipping steps and unknown statuses", func(t *testing.T) {
+		assert.False(t, CanTransitionOrder(OrderStatusPending, OrderStatusShipped))
+		assert.False(t, CanTransitionOrder(OrderStatusPaid, OrderStatusPending))
+		assert.False(t, CanTransitionOrder("completed", OrderStatusDelivered))
+		assert.False(t, IsValidOrderStatus("completed"))
+	})
+
+	t.Run("Every target is a known status", func(t *testing.T) {
+		for from, targets := range orderTransitions {
+			for _, to := range targets {
+				assert.True(t, IsValidOrderStatus(to), "%s -> %s", from, to)
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Session is a login on one device. Its ID is the refresh token family, so it
+// lives as long as the refresh token chain started at login.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	TokenID    string    `json:"jti"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
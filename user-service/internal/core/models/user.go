@@ -2,11 +2,78 @@ package models
 
 import "time"
 
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
+const (
+	UserStatusUnverified = "unverified"
+	UserStatusActive     = "active"
+	// UserStatusDeleted marks an account whose personal data was anonymized
+	// after deletion.
+	UserStatusDeleted = "deleted"
+)
+
 type User struct {
 	ID        string    `json:"id" bson:"_id,omitempty"`
 	Username  string    `json:"username" bson:"username"`
 	Email     string    `json:"email" bson:"email"`
 	Password  string    `json:"password" bson:"password"`
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	Roles     []string  `json:"roles" bson:"roles"`
+	Status    string    `json:"status" bson:"status"`
+	TwoFactor TwoFactor `json:"two_factor" bson:"two_factor"`
+	// Disabled accounts are blocked by an admin and cannot log in.
+	Disabled bool `json:"disabled" bson:"disabled,omitempty"`
+	// PasswordResetRequired is set when an admin forces a password reset. The
+	// current password stops working until a new one is set.
+	PasswordResetRequired bool `json:"password_reset_required" bson:"password_reset_required,omitempty"`
+	// Identities are the external identity provider accounts linked to the user.
+	Identities []ExternalIdentity `json:"identities,omitempty" bson:"identities,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+	// DeletedAt is set when the account is deleted. It can be restored until
+	// the grace period ends, then its personal data is anonymized and
+	// AnonymizedAt is set. Orders are kept either way.
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty" bson:"anonymized_at,omitempty"`
+}
+
+type ExternalIdentity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"subject" bson:"subject"`
+	Email    string    `json:"email" bson:"email"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
+}
+
+// TwoFactor holds the TOTP settings of a user. Secret is stored when enrollment
+// starts but only takes part in login once Enabled is set. Recovery codes are
+// kept as SHA-256 hashes and removed when used. Neither is serialized to JSON.
+type TwoFactor struct {
+	Enabled       bool      `json:"enabled" bson:"enabled"`
+	Secret        string    `json:"-" bson:"secret,omitempty"`
+	RecoveryCodes []string  `json:"-" bson:"recovery_codes,omitempty"`
+	EnabledAt     time.Time `json:"enabled_at,omitempty" bson:"enabled_at,omitempty"`
+}
+
+// IsEmailVerified reports whether the user confirmed their email address.
+// Accounts created before verification was introduced have no status and are
+// treated as verified.
+func (u User) IsEmailVerified() bool {
+	return u.Status != UserStatusUnverified
+}
+
+// IsDeleted reports whether the account was deleted, whether or not it is
+// still restorable.
+func (u User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+func HasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
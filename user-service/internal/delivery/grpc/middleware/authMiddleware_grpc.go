@@ -2,56 +2,142 @@ package middleware
 
 import (
 	"context"
-	"user-service/internal/infrastructure/utils/jwt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"user-service/internal/core/auth"
+	"user-service/internal/errors"
+	"user-service/internal/infrastructure/utils/jwt"
 )
 
-func JWTInterceptor(jwtService jwt.JWTService) grpc.UnaryServerInterceptor {
+// APIKeyAuthenticator resolves an x-api-key header into a service principal
+// allowed to call method.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey, method string) (*auth.Principal, error)
+}
+
+// JWTInterceptor authenticates protected methods with either a bearer JWT or,
+// for service-to-service calls, an x-api-key header. API keys are authorized
+// by their scopes rather than by the method's role policy.
+func JWTInterceptor(jwtService jwt.JWTService, apiKeys APIKeyAuthenticator, verification auth.EmailVerificationPolicy) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		switch info.FullMethod {
-		case "/ecommerce/.InventoryService/CreateProduct",
-			"/ecommerce/.InventoryService/UpdateProduct",
-			"/ecommerce/.InventoryService/DeleteProduct",
-			"/user.UserService/RetrieveProfile",
-			"/ecommerce/.order.OrderService/CreateOrder",
-			"/ecommerce/.order.OrderService/GetOrderByID",
-			"/ecommerce/.order.OrderService/UpdateOrder",
-			"/ecommerce/.order.OrderService/GetOrderByUserID":
-
-			md, ok := metadata.FromIncomingContext(ctx)
-			if !ok {
-				return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
-			}
+		policy, protected := methodPolicies[info.FullMethod]
+		if !protected {
+			return handler(ctx, req)
+		}
+
+		principal, err := authenticate(ctx, jwtService, apiKeys, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
 
-			authHeader := md["authorization"]
-			if len(authHeader) == 0 {
-				return nil, status.Errorf(codes.Unauthenticated, "authorization token is required")
+		if !principal.IsAPIKey() {
+			if err := policy.authorize(principal, req, verification); err != nil {
+				return nil, err
 			}
+		}
+
+		return handler(auth.WithPrincipal(ctx, principal), req)
+	}
+}
 
-			token := authHeader[0]
+// JWTStreamInterceptor is JWTInterceptor for streaming methods. The method
+// policy is checked against every received message, since the request is not
+// known when the stream opens.
+func JWTStreamInterceptor(jwtService jwt.JWTService, apiKeys APIKeyAuthenticator, verification auth.EmailVerificationPolicy) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		policy, protected := methodPolicies[info.FullMethod]
+		if !protected {
+			return handler(srv, ss)
+		}
 
-			if len(token) < 7 || token[:7] != "Bearer " {
-				return nil, status.Errorf(codes.Unauthenticated, "invalid token format")
-			}
-			token = token[7:]
+		principal, err := authenticate(ss.Context(), jwtService, apiKeys, info.FullMethod)
+		if err != nil {
+			return err
+		}
 
-			if token == "" {
-				return nil, status.Errorf(codes.Unauthenticated, "missing token")
-			}
+		return handler(srv, &authorizedStream{
+			ServerStream: ss,
+			ctx:          auth.WithPrincipal(ss.Context(), principal),
+			principal:    principal,
+			policy:       policy,
+			verification: verification,
+		})
+	}
+}
 
-			_, err := jwtService.VerifyToken(token)
-			if err != nil {
-				return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
-			}
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx          context.Context
+	principal    *auth.Principal
+	policy       MethodPolicy
+	verification auth.EmailVerificationPolicy
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.principal.IsAPIKey() {
+		return nil
+	}
+	return s.policy.authorize(s.principal, m, s.verification)
+}
+
+func authenticate(ctx context.Context, jwtService jwt.JWTService, apiKeys APIKeyAuthenticator, method string) (*auth.Principal, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "missing metadata")
+	}
+
+	if apiKey := md.Get("x-api-key"); len(apiKey) > 0 && apiKeys != nil {
+		return apiKeys.Authenticate(ctx, apiKey[0], method)
+	}
+
+	authHeader := md["authorization"]
+	if len(authHeader) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "authorization token is required")
+	}
+
+	token := authHeader[0]
+
+	if len(token) < 7 || token[:7] != "Bearer " {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token format")
+	}
+	token = token[7:]
+
+	if token == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "missing token")
+	}
+
+	claims, err := jwtService.VerifyToken(token)
+	if err != nil {
+		if err == errors.ErrTokenRevocationCheck {
+			return nil, status.Error(codes.Unavailable, err.Error())
 		}
-		return handler(ctx, req)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
 	}
+
+	return &auth.Principal{
+		Type:          auth.PrincipalUser,
+		UserID:        claims.UserID,
+		Roles:         claims.Roles,
+		EmailVerified: claims.EmailVerified,
+		SessionID:     claims.SessionID,
+	}, nil
 }
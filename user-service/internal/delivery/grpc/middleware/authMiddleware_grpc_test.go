@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	"user-service/internal/errors"
+	"user-service/internal/infrastructure/utils/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type stubJWTService struct {
+	claims map[string]*jwt.Claims
+}
+
+func (s *stubJWTService) GenerateJWT(identity jwt.Identity) (string, error) {
+	return "", nil
+}
+
+func (s *stubJWTService) GenerateTokenPair(identity jwt.Identity) (*jwt.TokenPair, error) {
+	return nil, nil
+}
+
+func (s *stubJWTService) RefreshToken(refreshToken string, lookup jwt.IdentityLookup) (*jwt.TokenPair, error) {
+	return nil, nil
+}
+
+func (s *stubJWTService) VerifyToken(token string) (*jwt.Claims, error) {
+	claims, ok := s.claims[token]
+	if !ok {
+		return nil, errors.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (s *stubJWTService) BlacklistToken(token string, ttl time.Duration) error {
+	return nil
+}
+
+func (s *stubJWTService) InvalidateToken(tokenString string) error {
+	return nil
+}
+
+func (s *stubJWTService) RevokeSession(sessionID string) error {
+	return nil
+}
+
+func (s *stubJWTService) RevokeAllTokens(userID string) error {
+	return nil
+}
+
+func (s *stubJWTService) ExtractTokenFromContext(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+type stubAPIKeys struct{}
+
+func (stubAPIKeys) Authenticate(ctx context.Context, rawKey, method string) (*auth.Principal, error) {
+	if rawKey != "batch-key" {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	principal := &auth.Principal{Type: auth.PrincipalAPIKey, APIKeyID: "key-1", Scopes: []string{"/ecommerce/.order.OrderService/GetOrderByUserID"}}
+	if !principal.HasScope(method) {
+		return nil, status.Error(codes.PermissionDenied, "API key is not allowed to call this method")
+	}
+	return principal, nil
+}
+
+type userRequest struct {
+	userID string
+}
+
+func (r *userRequest) GetUserId() string {
+	return r.userID
+}
+
+func TestJWTInterceptor(t *testing.T) {
+	interceptor := JWTInterceptor(&stubJWTService{claims: map[string]*jwt.Claims{
+		"customer-token":   {Identity: jwt.Identity{UserID: "user-1", Roles: []string{models.RoleCustomer}, EmailVerified: true}},
+		"unverified-token": {Identity: jwt.Identity{UserID: "user-3", Roles: []string{models.RoleCustomer}}},
+		"admin-token":      {Identity: jwt.Identity{UserID: "admin-1", Roles: []string{models.RoleAdmin}, EmailVerified: true}},
+	}}, stubAPIKeys{}, auth.EmailVerificationForOrders)
+
+	callWith := func(method string, md metadata.MD, req interface{}) (*auth.Principal, error) {
+		ctx := context.Background()
+		if md != nil {
+			ctx = metadata.NewIncomingContext(ctx, md)
+		}
+
+		var principal *auth.Principal
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			principal, _ = auth.PrincipalFromContext(ctx)
+			return nil, nil
+		})
+		return principal, err
+	}
+
+	call := func(method, token string, req interface{}) (*auth.Principal, error) {
+		if token == "" {
+			return callWith(method, nil, req)
+		}
+		return callWith(method, metadata.Pairs("authorization", "Bearer "+token), req)
+	}
+
+	t.Run("Unprotected method passes through", func(t *testing.T) {
+		principal, err := call("/user.UserService/LoginUser", "", nil)
+		assert.NoError(t, err)
+		assert.Nil(t, principal)
+	})
+
+	t.Run("Missing token is rejected", func(t *testing.T) {
+		_, err := call("/user.UserService/RetrieveProfile", "", &userRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Principal is propagated", func(t *testing.T) {
+		principal, err := call("/user.UserService/RetrieveProfile", "customer-token", &userRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", principal.UserID)
+	})
+
+	t.Run("Admin-only method rejects customers", func(t *testing.T) {
+		_, err := call("/ecommerce/.InventoryService/CreateProduct", "customer-token", nil)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = call("/ecommerce/.InventoryService/CreateProduct", "admin-token", nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Owner-only method rejects other users", func(t *testing.T) {
+		_, err := call("/ecommerce/.order.OrderService/GetOrderByUserID", "customer-token", &userRequest{userID: "user-2"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = call("/ecommerce/.order.OrderService/GetOrderByUserID", "admin-token", &userRequest{userID: "user-2"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unverified email cannot place orders", func(t *testing.T) {
+		_, err := call("/ecommerce/.order.OrderService/CreateOrder", "unverified-token", &userRequest{})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = call("/user.UserService/RetrieveProfile", "unverified-token", &userRequest{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("API key acts within its scopes", func(t *testing.T) {
+		principal, err := callWith("/ecommerce/.order.OrderService/GetOrderByUserID", metadata.Pairs("x-api-key", "batch-key"), &userRequest{userID: "user-2"})
+		assert.NoError(t, err)
+		assert.True(t, principal.IsAPIKey())
+
+		_, err = callWith("/user.UserService/DeleteUser", metadata.Pairs("x-api-key", "batch-key"), &userRequest{userID: "user-2"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = callWith("/user.UserService/DeleteUser", metadata.Pairs("x-api-key", "unknown"), &userRequest{userID: "user-2"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+type stubServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req *userRequest
+}
+
+func (s *stubServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *stubServerStream) RecvMsg(m interface{}) error {
+	*m.(*userRequest) = *s.req
+	return nil
+}
+
+func TestJWTStreamInterceptor(t *testing.T) {
+	interceptor := JWTStreamInterceptor(&stubJWTService{claims: map[string]*jwt.Claims{
+		"customer-token": {Identity: jwt.Identity{UserID: "user-1", Roles: []string{models.RoleCustomer}, EmailVerified: true}},
+	}}, stubAPIKeys{}, auth.EmailVerificationForOrders)
+
+	call := func(token string, req *userRequest) (*auth.Principal, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+
+		var principal *auth.Principal
+		err := interceptor(nil, &stubServerStream{ctx: ctx, req: req}, &grpc.StreamServerInfo{FullMethod: "/user.UserService/ExportUserData"},
+			func(srv interface{}, stream grpc.ServerStream) error {
+				principal, _ = auth.PrincipalFromContext(stream.Context())
+				return stream.RecvMsg(&userRequest{})
+			})
+		return principal, err
+	}
+
+	t.Run("Missing token is rejected", func(t *testing.T) {
+		_, err := call("", &userRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Owner can open the stream", func(t *testing.T) {
+		principal, err := call("customer-token", &userRequest{userID: "user-1"})
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", principal.UserID)
+	})
+
+	t.Run("Request for another user is rejected", func(t *testing.T) {
+		_, err := call("customer-token", &userRequest{userID: "user-2"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestAuthorizeScope(t *testing.T) {
+	admin := &auth.Principal{Type: auth.PrincipalUser, UserID: "admin-1", Roles: []string{models.RoleAdmin}}
+	customer := &auth.Principal{Type: auth.PrincipalUser, UserID: "user-1", Roles: []string{models.RoleCustomer}}
+
+	assert.NoError(t, AuthorizeScope(admin, "/user.UserService/SearchUsers"))
+	assert.NoError(t, AuthorizeScope(customer, "/ecommerce/.order.OrderService/GetOrderByUserID"))
+
+	err := AuthorizeScope(customer, "/user.UserService/SearchUsers")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	err = AuthorizeScope(admin, "/user.UserService/LoginUser")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = AuthorizeScope(admin, "/user.UserService/NoSuchMethod")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
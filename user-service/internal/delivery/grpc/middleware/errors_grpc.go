@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"user-service/internal/errors"
+)
+
+// ErrorInterceptor gives typed domain errors their gRPC status code. Errors
+// that already carry a status are returned unchanged.
+func ErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, toStatusError(err)
+	}
+}
+
+// ErrorStreamInterceptor is ErrorInterceptor for streaming methods.
+func ErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return toStatusError(handler(srv, ss))
+	}
+}
+
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var alreadyExists *errors.AlreadyExistsError
+	if stderrors.As(err, &alreadyExists) {
+		return status.Error(codes.AlreadyExists, alreadyExists.Error())
+	}
+	return err
+}
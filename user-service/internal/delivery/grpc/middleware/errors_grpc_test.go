@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+	"user-service/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorInterceptor(t *testing.T) {
+	interceptor := ErrorInterceptor()
+
+	call := func(handlerErr error) error {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/RegisterUser"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, handlerErr
+			})
+		return err
+	}
+
+	t.Run("Already exists error gets its status code", func(t *testing.T) {
+		err := call(fmt.Errorf("create user: %w", &errors.AlreadyExistsError{Field: "email"}))
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		assert.Equal(t, "user with this email already exists", status.Convert(err).Message())
+	})
+
+	t.Run("Status errors are kept", func(t *testing.T) {
+		err := call(status.Error(codes.NotFound, "user not found"))
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Other errors are passed through", func(t *testing.T) {
+		assert.NoError(t, call(nil))
+		assert.Equal(t, codes.Unknown, status.Code(call(stderrors.New("boom"))))
+	})
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+)
+
+// MethodPolicy describes who may call a protected RPC. A method with an empty
+// policy only requires a valid token.
+type MethodPolicy struct {
+	// Roles lists the roles allowed to call the method; any one of them is enough.
+	Roles []string
+	// OwnerOnly restricts the call to the user named by the request's user_id,
+	// unless the caller is an admin.
+	OwnerOnly bool
+	// VerifiedEmail requires a verified email address unless verification is
+	// optional.
+	VerifiedEmail bool
+}
+
+var adminOnly = MethodPolicy{Roles: []string{models.RoleAdmin}}
+
+var methodPolicies = map[string]MethodPolicy{
+	"/ecommerce/.InventoryService/CreateProduct": adminOnly,
+	"/ecommerce/.InventoryService/UpdateProduct": adminOnly,
+	"/ecommerce/.InventoryService/DeleteProduct": adminOnly,
+
+	"/user.UserService/RetrieveProfile":    {OwnerOnly: true},
+	"/user.UserService/UpdateProfile":      {OwnerOnly: true},
+	"/user.UserService/ChangePassword":     {},
+	"/user.UserService/EnrollTwoFactor":    {},
+	"/user.UserService/ConfirmTwoFactor":   {},
+	"/user.UserService/DisableTwoFactor":   {},
+	"/user.UserService/DeleteUser":         {OwnerOnly: true},
+	"/user.UserService/Logout":             {},
+	"/user.UserService/LogoutAllSessions":  {OwnerOnly: true},
+	"/user.UserService/ListSessions":       {OwnerOnly: true},
+	"/user.UserService/RevokeSession":      {OwnerOnly: true},
+	"/user.UserService/ExportUserData":     {OwnerOnly: true},
+	"/user.UserService/ListAuditEvents":    {OwnerOnly: true},
+	"/user.UserService/CreateAPIKey":       adminOnly,
+	"/user.UserService/ListAPIKeys":        adminOnly,
+	"/user.UserService/RevokeAPIKey":       adminOnly,
+	"/user.UserService/SearchUsers":        adminOnly,
+	"/user.UserService/DisableUser":        adminOnly,
+	"/user.UserService/EnableUser":         adminOnly,
+	"/user.UserService/ForcePasswordReset": adminOnly,
+	"/user.UserService/SetUserRoles":       adminOnly,
+
+	"/ecommerce/.order.OrderService/CreateOrder":      {OwnerOnly: true, VerifiedEmail: true},
+	"/ecommerce/.order.OrderService/GetOrderByID":     {},
+	"/ecommerce/.order.OrderService/UpdateOrder":      {},
+	"/ecommerce/.order.OrderService/GetOrderByUserID": {OwnerOnly: true},
+}
+
+type userScopedRequest interface {
+	GetUserId() string
+}
+
+func (p MethodPolicy) authorize(principal *auth.Principal, req interface{}, verification auth.EmailVerificationPolicy) error {
+	if len(p.Roles) > 0 && !hasAnyRole(principal, p.Roles) {
+		return status.Errorf(codes.PermissionDenied, "insufficient role for this operation")
+	}
+
+	if p.OwnerOnly && !principal.IsAdmin() {
+		if r, ok := req.(userScopedRequest); ok && r.GetUserId() != "" && r.GetUserId() != principal.UserID {
+			return status.Errorf(codes.PermissionDenied, "access to another user's resources is not allowed")
+		}
+	}
+
+	if p.VerifiedEmail && verification != auth.EmailVerificationOptional && !principal.EmailVerified {
+		return status.Errorf(codes.FailedPrecondition, "email address must be verified first")
+	}
+
+	return nil
+}
+
+// AuthorizeScope checks that principal may grant an API key access to method.
+// Only protected methods can be scoped, and only by callers whose roles let
+// them call the method themselves, since API keys skip the role policy.
+func AuthorizeScope(principal *auth.Principal, method string) error {
+	policy, ok := methodPolicies[method]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "scope %q is not a protected gRPC method", method)
+	}
+	if len(policy.Roles) > 0 && !hasAnyRole(principal, policy.Roles) {
+		return status.Errorf(codes.PermissionDenied, "you cannot grant access to %s", method)
+	}
+	return nil
+}
+
+func hasAnyRole(principal *auth.Principal, allowed []string) bool {
+	for _, role := range allowed {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,10 @@
 package controllers
 
 import (
-	"user-service/internal/core/models"
 	"user-service/internal/usecases/services"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
 	"net/http"
 )
@@ -12,6 +13,14 @@ type OrderController struct {
 	orderService services.OrderService
 }
 
+// orderErrorStatus maps the status codes of order service errors that the
+// client can act on to HTTP statuses.
+var orderErrorStatus = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.FailedPrecondition: http.StatusConflict,
+}
+
 func NewOrderController(orderService services.OrderService) *OrderController {
 	return &OrderController{
 		orderService: orderService,
@@ -20,12 +29,10 @@ func NewOrderController(orderService services.OrderService) *OrderController {
 
 func (ctrl *OrderController) CreateOrder(c *gin.Context) {
 	var orderRequest struct {
-		UserID string `json:"user_id" binding:"required"`
-		Status string `json:"status" binding:"required"`
-		Items  []struct {
+		Items []struct {
 			ProductID    string  `json:"product_id" binding:"required"`
 			Quantity     int     `json:"quantity" binding:"required"`
-			PricePerUnit float64 `json:"price_per_unit" binding:"required"`
+			PricePerUnit float64 `json:"price_per_unit"`
 		} `json:"items" binding:"required"`
 	}
 
@@ -34,10 +41,6 @@ func (ctrl *OrderController) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	if orderRequest.Status != "pending" && orderRequest.Status != "completed" && orderRequest.Status != "cancelled" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Allowed values are 'pending', 'completed', 'cancelled'."})
-		return
-	}
 	for _, item := range orderRequest.Items {
 		if item.Quantity < 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity cannot be negative"})
@@ -49,19 +52,23 @@ func (ctrl *OrderController) CreateOrder(c *gin.Context) {
 		}
 	}
 
-	items := []models.OrderItem{}
+	lines := []services.OrderLine{}
 	for _, item := range orderRequest.Items {
-		items = append(items, models.OrderItem{
-			ProductID:   item.ProductID,
-			Quantity:    item.Quantity,
-			PricePerUnit: item.PricePerUnit,
+		lines = append(lines, services.OrderLine{
+			ProductID:     item.ProductID,
+			Quantity:      item.Quantity,
+			ExpectedPrice: item.PricePerUnit,
 		})
 	}
 
 	userID := c.GetString("user_id")
 
-	order, err := ctrl.orderService.CreateOrder(c, userID, items, orderRequest.Status)
+	order, err := ctrl.orderService.CreateOrder(c.Request.Context(), userID, lines)
 	if err != nil {
+		if code, ok := orderErrorStatus[status.Code(err)]; ok {
+			c.JSON(code, gin.H{"error": status.Convert(err).Message()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 		return
 	}
@@ -71,7 +78,7 @@ func (ctrl *OrderController) CreateOrder(c *gin.Context) {
 
 func (ctrl *OrderController) GetOrderByID(c *gin.Context) {
 	id := c.Param("id")
-	order, err := ctrl.orderService.GetOrderByID(c, id)
+	order, err := ctrl.orderService.GetOrderByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
@@ -92,7 +99,7 @@ func (ctrl *OrderController) UpdateOrder(c *gin.Context) {
 		return
 	}
 
-	err := ctrl.orderService.UpdateOrder(c, id, orderUpdate.Status)
+	err := ctrl.orderService.UpdateOrder(c.Request.Context(), id, orderUpdate.Status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
 		return
@@ -101,12 +108,11 @@ func (ctrl *OrderController) UpdateOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully"})
 }
 
-
 func (ctrl *OrderController) GetOrdersByUserID(c *gin.Context) {
 	userID := c.Param("id")
 	log.Println("Fetching order with ID:", userID)
 
-	orders, err := ctrl.orderService.GetOrdersByUserID(c, userID)
+	orders, err := ctrl.orderService.GetOrdersByUserID(c.Request.Context(), userID)
 	if err != nil {
 		log.Println("Error fetching order:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
@@ -53,14 +53,29 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := uc.jwtService.GenerateJWT(user.ID)
+	if user.TwoFactor.Enabled {
+		challenge, err := uc.service.CreateTwoFactorChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating login challenge"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	tokens, err := uc.service.IssueTokens(c.Request.Context(), user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating JWT"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"user":  user,
-		"token": token,
+		"user":          user,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
 	})
 }
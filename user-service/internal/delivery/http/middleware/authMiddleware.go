@@ -1,27 +1,14 @@
 package middleware
 
 import (
-	"github.com/dgrijalva/jwt-go"
+	"user-service/internal/core/auth"
+	"user-service/internal/infrastructure/utils/jwt"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
-	"log"
 	"net/http"
-	"os"
 	"strings"
 )
 
-func AuthMiddleWare() gin.HandlerFunc {
-	err := godotenv.Load()
-
-	if err != nil {
-		log.Fatalf("Error loading .env file")
-	}
-
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		log.Fatalf("JWT_SECRET_KEY is not set in environment variables")
-	}
-
+func AuthMiddleWare(jwtService jwt.JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 
@@ -33,24 +20,22 @@ func AuthMiddleWare() gin.HandlerFunc {
 
 		token = strings.Replace(token, "Bearer ", "", 1)
 
-		claims := jwt.MapClaims{}
-		parsedToken, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secretKey), nil
-		})
-
-		if err != nil || !parsedToken.Valid {
+		claims, err := jwtService.VerifyToken(token)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-			c.Abort()
-			return
-		}
-		c.Set("user_id", userID)
+		c.Set("user_id", claims.UserID)
+		c.Set("roles", claims.Roles)
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), &auth.Principal{
+			Type:          auth.PrincipalUser,
+			UserID:        claims.UserID,
+			Roles:         claims.Roles,
+			EmailVerified: claims.EmailVerified,
+			SessionID:     claims.SessionID,
+		}))
 		c.Next()
 	}
 }
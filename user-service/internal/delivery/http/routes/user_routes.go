@@ -1,7 +1,7 @@
 package routes
 
 import (
-	"ecommerce/internal/delivery/http/controllers"
+	"user-service/internal/delivery/http/controllers"
 	"github.com/gin-gonic/gin"
 )
 
@@ -1,16 +1,56 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrPasswordHashing = errors.New("error hashing a password")
-	ErrJWTGeneration   = errors.New("error generating a JWT")
+	ErrPasswordHashing    = errors.New("error hashing a password")
+	ErrJWTGeneration      = errors.New("error generating a JWT")
 	ErrMissingName        = errors.New("product name is required")
 	ErrInvalidPrice       = errors.New("product price must be greater than zero")
+	ErrInvalidCurrency    = errors.New("price currency must be an ISO 4217 code")
+	ErrCurrencyMismatch   = errors.New("amounts in different currencies cannot be combined")
 	ErrInvalidStock       = errors.New("product stock must be greater than zero")
 	ErrMissingDescription = errors.New("product description is required")
 	ErrInvalidCategoryID  = errors.New("invalid categoryID format")
-	ErrInvalidToken  = errors.New("invalid or expired token")
-	ErrProductNotFound         = errors.New("product not found")
-	ErrInsufficientStock       = errors.New("insufficient stock")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrPermissionDenied   = errors.New("access to another user's resources is not allowed")
+	ErrUserIDRequired     = errors.New("user_id is required for requests made with an API key")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrProductNotFound    = errors.New("product not found")
+	ErrInsufficientStock  = errors.New("insufficient stock")
+
+	// ErrTokenRevocationCheck means a token could not be checked against the
+	// revocation state in the cache, so it is rejected.
+	ErrTokenRevocationCheck = errors.New("token revocation status is unavailable")
 )
+
+// AlreadyExistsError reports that a user with the same value of Field, such
+// as "email" or "username", already exists.
+type AlreadyExistsError struct {
+	Field string
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("user with this %s already exists", e.Field)
+}
+
+// RefreshTokenReuseError is ErrRefreshTokenReused for the token family
+// SessionID of UserID, which has been revoked.
+type RefreshTokenReuseError struct {
+	UserID    string
+	SessionID string
+}
+
+func (e *RefreshTokenReuseError) Error() string {
+	return ErrRefreshTokenReused.Error()
+}
+
+func (e *RefreshTokenReuseError) Is(target error) bool {
+	return target == ErrRefreshTokenReused
+}
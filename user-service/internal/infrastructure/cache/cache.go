@@ -1,11 +1,36 @@
 package cache
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrCacheMiss is returned by Get and GetAndDelete for keys that do not exist,
+// as opposed to errors reaching the cache.
+var ErrCacheMiss = errors.New("cache: key not found")
 
 type CacheService interface {
 	Set(key string, value string, expiration time.Duration) error
+	// SetIfAbsent sets key only if it does not exist yet and reports whether
+	// it did.
+	SetIfAbsent(key string, value string, expiration time.Duration) (bool, error)
 	Get(key string) (string, error)
+	// GetAndDelete returns the value of key and deletes it in one step, so
+	// that only one of several concurrent callers gets the value.
+	GetAndDelete(key string) (string, error)
+	// CompareAndSwap sets key to value only if it currently holds old, and
+	// reports whether it did.
+	CompareAndSwap(key, old, value string, expiration time.Duration) (bool, error)
 	Delete(key string) error
-	InvalidateKeysByPrefix (prefix string) error
+	InvalidateKeysByPrefix(prefix string) error
 	Exists(key string) (bool, error)
+	Increment(key string) (int64, error)
+	IncrementWithTTL(key string, expiration time.Duration) (int64, error)
+	TTL(key string) (time.Duration, error)
+	// AddToSet adds member to the set at key and extends the expiration of
+	// the set to at least expiration, so that it lives as long as its longest
+	// lived member.
+	AddToSet(key, member string, expiration time.Duration) error
+	RemoveFromSet(key, member string) error
+	SetMembers(key string) ([]string, error)
 }
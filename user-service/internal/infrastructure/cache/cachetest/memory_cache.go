@@ -0,0 +1,240 @@
+// Package cachetest provides an in-memory cache.CacheService for tests.
+package cachetest
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"user-service/internal/infrastructure/cache"
+)
+
+var _ cache.CacheService = (*MemoryCache)(nil)
+
+// MemoryCache keeps values in a map and honours expirations, so that tests of
+// lockouts and token lifetimes behave like they do against Redis. It is safe
+// for concurrent use.
+type MemoryCache struct {
+	mu      sync.Mutex
+	data    map[string]string
+	sets    map[string]map[string]struct{}
+	expires map[string]time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		data:    map[string]string{},
+		sets:    map[string]map[string]struct{}{},
+		expires: map[string]time.Time{},
+	}
+}
+
+func (c *MemoryCache) Set(key string, value string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, expiration)
+	return nil
+}
+
+func (c *MemoryCache) SetIfAbsent(key string, value string, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.get(key); ok {
+		return false, nil
+	}
+	c.set(key, value, expiration)
+	return true, nil
+}
+
+func (c *MemoryCache) Get(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.get(key)
+	if !ok {
+		return "", cache.ErrCacheMiss
+	}
+	return value, nil
+}
+
+func (c *MemoryCache) GetAndDelete(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.get(key)
+	if !ok {
+		return "", cache.ErrCacheMiss
+	}
+	c.delete(key)
+	return value, nil
+}
+
+func (c *MemoryCache) CompareAndSwap(key, old, value string, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.get(key); !ok || current != old {
+		return false, nil
+	}
+	c.set(key, value, expiration)
+	return true, nil
+}
+
+func (c *MemoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delete(key)
+	return nil
+}
+
+func (c *MemoryCache) InvalidateKeysByPrefix(prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.data {
+		if strings.HasPrefix(key, prefix) {
+			c.delete(key)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Exists(key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.exists(key), nil
+}
+
+func (c *MemoryCache) Increment(key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.increment(key), nil
+}
+
+func (c *MemoryCache) IncrementWithTTL(key string, expiration time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value := c.increment(key)
+	if _, ok := c.expires[key]; !ok && expiration > 0 {
+		c.expires[key] = time.Now().Add(expiration)
+	}
+	return value, nil
+}
+
+func (c *MemoryCache) TTL(key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.exists(key) {
+		return -2, nil
+	}
+	expiresAt, ok := c.expires[key]
+	if !ok {
+		return -1, nil
+	}
+	return time.Until(expiresAt), nil
+}
+
+func (c *MemoryCache) AddToSet(key, member string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set, ok := c.members(key)
+	if !ok {
+		set = map[string]struct{}{}
+		c.sets[key] = set
+		delete(c.expires, key)
+	}
+	set[member] = struct{}{}
+	if expiresAt := time.Now().Add(expiration); expiration > 0 && c.expires[key].Before(expiresAt) {
+		c.expires[key] = expiresAt
+	}
+	return nil
+}
+
+func (c *MemoryCache) RemoveFromSet(key, member string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if set, ok := c.members(key); ok {
+		delete(set, member)
+		if len(set) == 0 {
+			c.delete(key)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) SetMembers(key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	set, _ := c.members(key)
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// Expire makes key expire immediately, as if its TTL had run out.
+func (c *MemoryCache) Expire(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delete(key)
+}
+
+func (c *MemoryCache) get(key string) (string, bool) {
+	c.expire(key)
+	value, ok := c.data[key]
+	return value, ok
+}
+
+func (c *MemoryCache) members(key string) (map[string]struct{}, bool) {
+	c.expire(key)
+	set, ok := c.sets[key]
+	return set, ok
+}
+
+func (c *MemoryCache) exists(key string) bool {
+	_, isValue := c.get(key)
+	_, isSet := c.members(key)
+	return isValue || isSet
+}
+
+// expire deletes key if its expiration has passed.
+func (c *MemoryCache) expire(key string) {
+	if expiresAt, ok := c.expires[key]; ok && !time.Now().Before(expiresAt) {
+		c.delete(key)
+	}
+}
+
+func (c *MemoryCache) set(key, value string, expiration time.Duration) {
+	c.data[key] = value
+	if expiration > 0 {
+		c.expires[key] = time.Now().Add(expiration)
+	} else {
+		delete(c.expires, key)
+	}
+}
+
+func (c *MemoryCache) delete(key string) {
+	delete(c.data, key)
+	delete(c.sets, key)
+	delete(c.expires, key)
+}
+
+func (c *MemoryCache) increment(key string) int64 {
+	current, _ := c.get(key)
+	value, _ := strconv.ParseInt(current, 10, 64)
+	value++
+	c.data[key] = strconv.FormatInt(value, 10)
+	return value
+}
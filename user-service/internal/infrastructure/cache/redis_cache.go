@@ -15,9 +15,9 @@ type RedisCache struct {
 
 func NewRedisCache(addr, password string, db int) *RedisCache {
 	rdb := redis.NewClient(&redis.Options{
-		Addr: addr,
+		Addr:     addr,
 		Password: password,
-		DB: db,
+		DB:       db,
 	})
 
 	_, err := rdb.Ping(ctx).Result()
@@ -35,8 +35,30 @@ func (r *RedisCache) Set(key string, value string, expiration time.Duration) err
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+func (r *RedisCache) SetIfAbsent(key string, value string, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (r *RedisCache) Get(key string) (string, error) {
-	return r.client.Get(ctx, key).Result()
+	return missing(r.client.Get(ctx, key).Result())
+}
+
+func (r *RedisCache) GetAndDelete(key string) (string, error) {
+	return missing(r.client.GetDel(ctx, key).Result())
+}
+
+// compareAndSwap sets a key to ARGV[2] if it holds ARGV[1].
+var compareAndSwap = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0
+`)
+
+func (r *RedisCache) CompareAndSwap(key, old, value string, expiration time.Duration) (bool, error) {
+	swapped, err := compareAndSwap.Run(ctx, r.client, []string{key}, old, value, expiration.Milliseconds()).Int64()
+	return swapped == 1, err
 }
 
 func (r *RedisCache) Delete(key string) error {
@@ -56,6 +78,28 @@ func (r *RedisCache) InvalidateKeysByPrefix(prefix string) error {
 	return nil
 }
 
+// addToSet adds ARGV[1] to a set and raises its expiration to ARGV[2]
+// milliseconds if it would expire sooner.
+var addToSet = redis.NewScript(`
+redis.call("SADD", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+func (r *RedisCache) AddToSet(key, member string, expiration time.Duration) error {
+	return addToSet.Run(ctx, r.client, []string{key}, member, expiration.Milliseconds()).Err()
+}
+
+func (r *RedisCache) RemoveFromSet(key, member string) error {
+	return r.client.SRem(ctx, key, member).Err()
+}
+
+func (r *RedisCache) SetMembers(key string) ([]string, error) {
+	return r.client.SMembers(ctx, key).Result()
+}
+
 func (r *RedisCache) Exists(token string) (bool, error) {
 	ctx := context.Background()
 	res, err := r.client.Exists(ctx, token).Result()
@@ -64,3 +108,36 @@ func (r *RedisCache) Exists(token string) (bool, error) {
 	}
 	return res == 1, nil
 }
+
+func (r *RedisCache) Increment(key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
+// incrementWithTTL increments a counter and sets its expiration in one step.
+// A counter without an expiration, left over from a failed EXPIRE, gets one
+// too instead of living forever.
+var incrementWithTTL = redis.NewScript(`
+local value = redis.call("INCR", KEYS[1])
+if value == 1 or redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return value
+`)
+
+// IncrementWithTTL increments a counter and starts its expiration on the first
+// increment, so the counter covers a fixed window.
+func (r *RedisCache) IncrementWithTTL(key string, expiration time.Duration) (int64, error) {
+	return incrementWithTTL.Run(ctx, r.client, []string{key}, expiration.Milliseconds()).Int64()
+}
+
+// missing turns the redis.Nil reply for an absent key into ErrCacheMiss.
+func missing(value string, err error) (string, error) {
+	if err == redis.Nil {
+		return "", ErrCacheMiss
+	}
+	return value, err
+}
+
+func (r *RedisCache) TTL(key string) (time.Duration, error) {
+	return r.client.TTL(ctx, key).Result()
+}
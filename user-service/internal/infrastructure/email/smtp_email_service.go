@@ -1,17 +1,70 @@
 package email
 
 import (
+	"bytes"
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"log"
 	"os"
+	"text/template"
+	"time"
 )
 
+const defaultPasswordResetTemplate = `Здравствуйте!
+
+Мы получили запрос на сброс пароля для вашей учётной записи.
+Чтобы задать новый пароль, перейдите по ссылке (она действует {{.ExpiresIn}}):
+
+{{.ResetURL}}
+
+Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.
+`
+
+const defaultVerificationTemplate = `Здравствуйте!
+
+Подтвердите адрес электронной почты, перейдя по ссылке (она действует {{.ExpiresIn}}):
+
+{{.VerificationURL}}
+
+Если вы не регистрировались, просто проигнорируйте это письмо.
+`
+
+const defaultAccountLockedTemplate = `Здравствуйте!
+
+Мы зафиксировали несколько неудачных попыток входа в вашу учётную запись,
+поэтому вход временно заблокирован на {{.LockedFor}}.
+
+Если это были вы, учётную запись можно разблокировать сразу по ссылке:
+
+{{.UnlockURL}}
+
+Если вы не пытались войти, рекомендуем сменить пароль.
+`
+
+const defaultAccountDeletedTemplate = `Здравствуйте!
+
+Ваша учётная запись удалена. В течение {{.RestorableFor}} её можно восстановить по ссылке:
+
+{{.RestoreURL}}
+
+После этого личные данные будут безвозвратно обезличены.
+`
+
 type SMTPEmailService struct {
 	from     string
 	host     string
 	port     int
 	username string
 	password string
+
+	passwordResetURL       string
+	passwordResetTemplate  *template.Template
+	verificationURL        string
+	verificationTemplate   *template.Template
+	unlockURL              string
+	accountLockedTemplate  *template.Template
+	restoreURL             string
+	accountDeletedTemplate *template.Template
 }
 
 func NewSMTPEmailService() *SMTPEmailService {
@@ -21,6 +74,15 @@ func NewSMTPEmailService() *SMTPEmailService {
 		port:     getEnvAsInt("SMTP_PORT", 587),
 		username: os.Getenv("SMTP_USERNAME"),
 		password: os.Getenv("SMTP_PASSWORD"),
+
+		passwordResetURL:       os.Getenv("PASSWORD_RESET_URL"),
+		passwordResetTemplate:  loadTemplate("password_reset", "PASSWORD_RESET_EMAIL_TEMPLATE", defaultPasswordResetTemplate),
+		verificationURL:        os.Getenv("EMAIL_VERIFICATION_URL"),
+		verificationTemplate:   loadTemplate("verification", "VERIFICATION_EMAIL_TEMPLATE", defaultVerificationTemplate),
+		unlockURL:              os.Getenv("ACCOUNT_UNLOCK_URL"),
+		accountLockedTemplate:  loadTemplate("account_locked", "ACCOUNT_LOCKED_EMAIL_TEMPLATE", defaultAccountLockedTemplate),
+		restoreURL:             os.Getenv("ACCOUNT_RESTORE_URL"),
+		accountDeletedTemplate: loadTemplate("account_deleted", "ACCOUNT_DELETED_EMAIL_TEMPLATE", defaultAccountDeletedTemplate),
 	}
 }
 
@@ -28,6 +90,62 @@ func (s *SMTPEmailService) SendWelcomeEmail(to string) error {
 	subject := "Добро пожаловать!"
 	body := os.Getenv("WELCOME_EMAIL_TEMPLATE") // простой текст
 
+	return s.send(to, subject, body)
+}
+
+func (s *SMTPEmailService) SendPasswordResetEmail(to, token string, expiresIn time.Duration) error {
+	body, err := render(s.passwordResetTemplate, map[string]string{
+		"ResetURL":  s.passwordResetURL + token,
+		"Token":     token,
+		"ExpiresIn": expiresIn.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.send(to, "Сброс пароля", body)
+}
+
+func (s *SMTPEmailService) SendVerificationEmail(to, token string, expiresIn time.Duration) error {
+	body, err := render(s.verificationTemplate, map[string]string{
+		"VerificationURL": s.verificationURL + token,
+		"Token":           token,
+		"ExpiresIn":       expiresIn.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.send(to, "Подтверждение адреса электронной почты", body)
+}
+
+func (s *SMTPEmailService) SendAccountLockedEmail(to, unlockToken string, lockedFor time.Duration) error {
+	body, err := render(s.accountLockedTemplate, map[string]string{
+		"UnlockURL": s.unlockURL + unlockToken,
+		"Token":     unlockToken,
+		"LockedFor": lockedFor.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.send(to, "Вход в учётную запись временно заблокирован", body)
+}
+
+func (s *SMTPEmailService) SendAccountDeletedEmail(to, restoreToken string, restorableFor time.Duration) error {
+	body, err := render(s.accountDeletedTemplate, map[string]string{
+		"RestoreURL":    s.restoreURL + restoreToken,
+		"Token":         restoreToken,
+		"RestorableFor": restorableFor.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.send(to, "Учётная запись удалена", body)
+}
+
+func (s *SMTPEmailService) send(to, subject, body string) error {
 	m := gomail.NewMessage()
 	m.SetHeader("From", s.from)
 	m.SetHeader("To", to)
@@ -38,6 +156,26 @@ func (s *SMTPEmailService) SendWelcomeEmail(to string) error {
 	return d.DialAndSend(m)
 }
 
+// шаблон письма можно переопределить через переменную окружения
+func loadTemplate(name, envKey, fallback string) *template.Template {
+	if text := os.Getenv(envKey); text != "" {
+		tmpl, err := template.New(name).Parse(text)
+		if err == nil {
+			return tmpl
+		}
+		log.Printf("Invalid %s, using the default template: %v", envKey, err)
+	}
+	return template.Must(template.New(name).Parse(fallback))
+}
+
+func render(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s email: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
 // утилита
 func getEnvAsInt(key string, defaultVal int) int {
 	valStr := os.Getenv(key)
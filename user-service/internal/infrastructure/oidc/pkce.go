@@ -0,0 +1,19 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"user-service/internal/infrastructure/utils/security"
+)
+
+// GenerateCodeVerifier returns a PKCE code verifier (RFC 7636) of 43 characters.
+func GenerateCodeVerifier() (string, error) {
+	return security.GenerateToken(32)
+}
+
+// CodeChallengeS256 derives the S256 code challenge sent with the
+// authorization request.
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOIDCServer is a minimal OpenID provider: discovery, JWKS and a token
+// endpoint that checks PKCE before returning a signed ID token.
+type mockOIDCServer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	m := &mockOIDCServer{key: key, kid: "key-1", codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": m.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		m.mu.Lock()
+		authz, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+
+		if !ok || CodeChallengeS256(r.PostForm.Get("code_verifier")) != authz.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": m.sign(t, authz.claims)})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockOIDCServer) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	assert.NoError(t, err)
+	return signed
+}
+
+// authorize simulates the user signing in at the provider and returns the
+// authorization code sent back to the redirect URL.
+func (m *mockOIDCServer) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+
+	query := parsed.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	claims["nonce"] = query.Get("nonce")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes["code-1"] = mockAuthorization{challenge: query.Get("code_challenge"), claims: claims}
+	return "code-1"
+}
+
+func (m *mockOIDCServer) claims(clientID string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.URL,
+		"aud":            clientID,
+		"sub":            "subject-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newTestProvider(t *testing.T, server *mockOIDCServer) *Provider {
+	provider, err := NewProvider(context.Background(), ProviderConfig{
+		Name:        "mock",
+		Issuer:      server.URL,
+		ClientID:    "client-1",
+		RedirectURL: "https://shop.example.com/oidc/callback",
+	}, server.Client())
+	assert.NoError(t, err)
+	return provider
+}
+
+func TestProviderLogin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Completes the authorization code flow", func(t *testing.T) {
+		server := newMockOIDCServer(t)
+		provider := newTestProvider(t, server)
+
+		verifier, err := GenerateCodeVerifier()
+		assert.NoError(t, err)
+
+		authURL := provider.AuthCodeURL("state-1", "nonce-1", verifier)
+		code := server.authorize(t, authURL, server.claims("client-1"))
+
+		idToken, err := provider.Exchange(ctx, code, verifier)
+		assert.NoError(t, err)
+
+		claims, err := provider.VerifyIDToken(ctx, idToken, "nonce-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "subject-1", claims.Subject)
+		assert.Equal(t, "user@example.com", claims.Email)
+		assert.True(t, claims.EmailVerified)
+	})
+
+	t.Run("Rejects a wrong code verifier", func(t *testing.T) {
+		server := newMockOIDCServer(t)
+		provider := newTestProvider(t, server)
+
+		verifier, _ := GenerateCodeVerifier()
+		code := server.authorize(t, provider.AuthCodeURL("state-1", "nonce-1", verifier), server.claims("client-1"))
+
+		_, err := provider.Exchange(ctx, code, "another-verifier")
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects tokens with a wrong nonce, audience or expiry", func(t *testing.T) {
+		server := newMockOIDCServer(t)
+		provider := newTestProvider(t, server)
+
+		valid := server.claims("client-1")
+		valid["nonce"] = "nonce-1"
+		_, err := provider.VerifyIDToken(ctx, server.sign(t, valid), "nonce-2")
+		assert.Error(t, err)
+
+		otherClient := server.claims("client-2")
+		otherClient["nonce"] = "nonce-1"
+		_, err = provider.VerifyIDToken(ctx, server.sign(t, otherClient), "nonce-1")
+		assert.Error(t, err)
+
+		expired := server.claims("client-1")
+		expired["nonce"] = "nonce-1"
+		expired["exp"] = time.Now().Add(-time.Hour).Unix()
+		_, err = provider.VerifyIDToken(ctx, server.sign(t, expired), "nonce-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects tokens signed by another key", func(t *testing.T) {
+		server := newMockOIDCServer(t)
+		provider := newTestProvider(t, server)
+
+		claims := server.claims("client-1")
+		claims["nonce"] = "nonce-1"
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = server.kid
+		forged, err := token.SignedString(otherKey)
+		assert.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, forged, "nonce-1")
+		assert.Error(t, err)
+	})
+}
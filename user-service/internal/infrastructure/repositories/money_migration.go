@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"math"
+	"user-service/internal/core/models"
+)
+
+// legacyPriceFilter matches documents whose field still holds a float price
+// written before prices became models.Money.
+func legacyPriceFilter(field string) bson.M {
+	return bson.M{field: bson.M{"$type": "number"}}
+}
+
+// legacyPriceToMoney is an aggregation expression that turns the float price
+// at path into a models.Money document in currency.
+func legacyPriceToMoney(path string, currency string) bson.M {
+	factor := math.Pow10(models.MinorUnitDigits(currency))
+	return bson.M{
+		"amount":   bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{path, factor}}, 0}}},
+		"currency": bson.M{"$literal": currency},
+	}
+}
@@ -0,0 +1,70 @@
+//go:build integration
+// +build integration
+
+package repositories
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+	"user-service/internal/core/models"
+)
+
+func TestMigrateProductMoney_Integration(t *testing.T) {
+	ctx := context.Background()
+	db := connectTestDatabase(t)
+	repo := NewProductRepositoryMongo(db)
+
+	_, err := db.Collection("products").InsertMany(ctx, []interface{}{
+		bson.M{"_id": "legacy", "name": "Legacy", "price": 19.99, "stock": 3},
+		bson.M{"_id": "current", "name": "Current", "price": bson.M{"amount": 500, "currency": "EUR"}, "stock": 3},
+	})
+	require.NoError(t, err)
+
+	migrated, err := repo.MigrateMoney(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), migrated)
+
+	product, err := repo.GetProductByID(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, models.Money{Amount: 1999, Currency: "USD"}, product.Price)
+	assert.Equal(t, 3, product.Stock)
+
+	product, err = repo.GetProductByID(ctx, "current")
+	require.NoError(t, err)
+	assert.Equal(t, models.Money{Amount: 500, Currency: "EUR"}, product.Price)
+
+	migrated, err = repo.MigrateMoney(ctx, "USD")
+	require.NoError(t, err)
+	assert.Zero(t, migrated)
+}
+
+func TestMigrateOrderMoney_Integration(t *testing.T) {
+	ctx := context.Background()
+	db := connectTestDatabase(t)
+	repo := NewOrderRepositoryMongo(db)
+
+	_, err := db.Collection("orders").InsertOne(ctx, bson.M{
+		"order_id":    "legacy",
+		"status":      "pending",
+		"total_price": 25.5,
+		"items": bson.A{
+			bson.M{"product_id": "p1", "quantity": 2, "price_per_unit": 10.25},
+			bson.M{"product_id": "p2", "quantity": 1, "price_per_unit": 5.0},
+		},
+	})
+	require.NoError(t, err)
+
+	migrated, err := repo.MigrateMoney(ctx, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), migrated)
+
+	order, err := repo.GetOrderByID(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, models.Money{Amount: 2550, Currency: "USD"}, order.TotalPrice)
+	assert.Equal(t, "p1", order.Items[0].ProductID)
+	assert.Equal(t, 2, order.Items[0].Quantity)
+	assert.Equal(t, models.Money{Amount: 1025, Currency: "USD"}, order.Items[0].PricePerUnit)
+}
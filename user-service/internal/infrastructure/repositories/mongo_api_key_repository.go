@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/errors"
+	"user-service/internal/interfaces/repositories"
+)
+
+type apiKeyRepositoryMongo struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepositoryMongo(db *mongo.Database) repositories.APIKeyRepository {
+	return &apiKeyRepositoryMongo{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (r *apiKeyRepositoryMongo) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+func (r *apiKeyRepositoryMongo) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"hash": hash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.APIKey{}, errors.ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepositoryMongo) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"created_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []models.APIKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepositoryMongo) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": revokedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepositoryMongo) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_used_at": usedAt}})
+	return err
+}
+
+// EnsureIndexes creates the unique index on hash that keys are looked up by.
+func (r *apiKeyRepositoryMongo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetName("hash_unique").SetUnique(true),
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"user-service/internal/core/models"
+	"user-service/internal/interfaces/repositories"
+)
+
+type auditRepositoryMongo struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepositoryMongo(db *mongo.Database) repositories.AuditRepository {
+	return &auditRepositoryMongo{
+		collection: db.Collection("audit_events"),
+	}
+}
+
+func (r *auditRepositoryMongo) InsertEvents(ctx context.Context, events []models.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		if event.ID == "" {
+			event.ID = uuid.New().String()
+		}
+		docs[i] = event
+	}
+
+	// unordered so that one bad event does not drop the rest of the batch
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+func (r *auditRepositoryMongo) ListEvents(ctx context.Context, targetID, action string, skip, limit int64) ([]models.AuditEvent, int64, error) {
+	filter := bson.M{"target_id": targetID}
+	if action != "" {
+		filter["action"] = action
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (r *auditRepositoryMongo) AnonymizeEvents(ctx context.Context, userID string) (int64, error) {
+	filter := bson.M{"$or": bson.A{bson.M{"target_id": userID}, bson.M{"actor_id": userID}}}
+	update := bson.M{"$unset": bson.M{"ip": "", "user_agent": "", "details.email": ""}}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *auditRepositoryMongo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "target_id", Value: 1}, {Key: "occurred_at", Value: -1}},
+		Options: options.Index().SetName("target_id_occurred_at"),
+	})
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 	"user-service/internal/core/models"
 	"user-service/internal/interfaces/repositories"
@@ -37,15 +38,40 @@ func (r *orderRepositoryMongo) GetOrderByID(ctx context.Context, id string) (*mo
 	return &order, nil
 }
 
-func (r *orderRepositoryMongo) UpdateOrder(ctx context.Context, id string, status string) error {
+func (r *orderRepositoryMongo) TransitionOrderStatus(ctx context.Context, id string, from string, change models.OrderStatusChange) (bool, error) {
+	filter := bson.M{"order_id": id, "status": from}
 	update := bson.M{
 		"$set": bson.M{
-			"status":     status,
-			"updated_at": time.Now(),
+			"status":     change.Status,
+			"updated_at": change.ChangedAt,
 		},
+		"$push": bson.M{"status_history": change},
 	}
-	_, err := r.collection.UpdateByID(ctx, id, update)
-	return err
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+func (r *orderRepositoryMongo) GetExpiredReservations(ctx context.Context, before time.Time, limit int64) ([]*models.Order, error) {
+	filter := bson.M{
+		"status":                 models.OrderStatusPending,
+		"reservation_expires_at": bson.M{"$lt": before},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orders []*models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
 
 func (r *orderRepositoryMongo) GetOrdersByUserID(ctx context.Context, userID string) ([]*models.Order, error) {
@@ -74,7 +100,42 @@ func (r *orderRepositoryMongo) GetOrdersByUserID(ctx context.Context, userID str
 	return orders, nil
 }
 
-func (r *orderRepositoryMongo) DeleteOrdersByUserID(ctx context.Context, userID string) error {
-	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
-	return err
+func (r *orderRepositoryMongo) MigrateMoney(ctx context.Context, currency string) (int64, error) {
+	itemPrice := bson.M{"$map": bson.M{
+		"input": "$items",
+		"as":    "item",
+		"in": bson.M{"$mergeObjects": bson.A{
+			"$$item",
+			bson.M{"price_per_unit": legacyPriceToMoney("$$item.price_per_unit", currency)},
+		}},
+	}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"total_price": legacyPriceToMoney("$total_price", currency),
+		"items":       itemPrice,
+	}}}}
+
+	result, err := r.collection.UpdateMany(ctx, legacyPriceFilter("total_price"), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *orderRepositoryMongo) MigrateStatuses(ctx context.Context) (int64, error) {
+	legacy := models.LegacyOrderStatusCompleted
+
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"status_history.status": legacy},
+		bson.M{"$set": bson.M{"status_history.$[entry].status": models.OrderStatusDelivered}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"entry.status": legacy}}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.collection.UpdateMany(ctx, bson.M{"status": legacy}, bson.M{"$set": bson.M{"status": models.OrderStatusDelivered}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
 }
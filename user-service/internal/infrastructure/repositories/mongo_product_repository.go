@@ -8,7 +8,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"time"
 	"user-service/internal/core/models"
+	"user-service/internal/errors"
 	"user-service/internal/interfaces/repositories"
 )
 
@@ -63,6 +65,9 @@ func (r *ProductRepositoryMongo) GetProductByID(ctx context.Context, id string)
 	var product models.Product
 	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Product{}, errors.ErrProductNotFound
+		}
 		return models.Product{}, err
 	}
 	return product, nil
@@ -136,43 +141,42 @@ func (r *ProductRepositoryMongo) ListProducts(ctx context.Context, filter map[st
 	return products, nil
 }
 
-func (r *ProductRepositoryMongo) DecreaseStock(ctx context.Context, productID string, quantity int) (models.Product, error) {
-	log.Printf("Attempting to decrease stock for product %s by %d", productID, quantity)
-
-	var product models.Product
+func (r *ProductRepositoryMongo) MigrateMoney(ctx context.Context, currency string) (int64, error) {
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"price": legacyPriceToMoney("$price", currency)}}}}
 
-	err := r.collection.FindOne(ctx, bson.M{"_id": productID}).Decode(&product)
+	result, err := r.collection.UpdateMany(ctx, legacyPriceFilter("price"), update)
 	if err != nil {
-		return models.Product{}, fmt.Errorf("product not found: %w", err)
-	}
-
-	if product.Stock < quantity {
-		return models.Product{}, fmt.Errorf("insufficient stock")
+		return 0, err
 	}
+	return result.ModifiedCount, nil
+}
 
+func (r *ProductRepositoryMongo) ReserveStock(ctx context.Context, productID string, quantity int) (bool, error) {
+	filter := bson.M{"_id": productID, "stock": bson.M{"$gte": quantity}}
 	update := bson.M{
 		"$inc": bson.M{"stock": -quantity},
+		"$set": bson.M{"updated_at": time.Now()},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": productID}, update)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return models.Product{}, fmt.Errorf("failed to decrease stock: %w", err)
-	}
-
-	if result.MatchedCount == 0 {
-		log.Printf("No matching product found for ID %s", productID)
+		return false, fmt.Errorf("failed to reserve stock: %w", err)
 	}
+	return result.MatchedCount > 0, nil
+}
 
-	if result.ModifiedCount == 0 {
-		log.Printf("Stock not decreased for product %s", productID)
-	} else {
-		log.Printf("Stock decreased successfully for product %s", productID)
+func (r *ProductRepositoryMongo) ReleaseStock(ctx context.Context, productID string, quantity int) error {
+	update := bson.M{
+		"$inc": bson.M{"stock": quantity},
+		"$set": bson.M{"updated_at": time.Now()},
 	}
 
-	err = r.collection.FindOne(ctx, bson.M{"_id": productID}).Decode(&product)
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": productID}, update)
 	if err != nil {
-		return models.Product{}, fmt.Errorf("failed to retrieve updated product: %w", err)
+		return fmt.Errorf("failed to release stock: %w", err)
 	}
-
-	return product, nil
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("product not found")
+	}
+	return nil
 }
@@ -2,25 +2,41 @@ package repositories
 
 import (
 	"context"
-	"user-service/internal/core/models"
-	"user-service/internal/infrastructure/utils/security"
-	"user-service/internal/interfaces/repositories"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"regexp"
+	"strings"
 	"time"
+	"user-service/internal/core/models"
+	apperrors "user-service/internal/errors"
+	"user-service/internal/infrastructure/utils/security"
+	"user-service/internal/interfaces/repositories"
 )
 
+// emailCollation compares emails case-insensitively. Email lookups use it so
+// that they match the unique email index.
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// uniqueIndexFields maps unique index names to the field they protect.
+var uniqueIndexFields = map[string]string{
+	"email_unique":    "email",
+	"username_unique": "username",
+	"identity_unique": "identity",
+}
+
 type userRepositoryMongo struct {
-	collection *mongo.Collection
+	collection   *mongo.Collection
 	passwordHash security.PasswordHash
 }
 
 func NewUserRepositoryMongo(db *mongo.Database, passwordHash security.PasswordHash) repositories.UserRepository {
 	return &userRepositoryMongo{
-		collection:     db.Collection("users"),
+		collection:   db.Collection("users"),
 		passwordHash: passwordHash,
 	}
 }
@@ -32,7 +48,7 @@ func (r *userRepositoryMongo) CreateUser(ctx context.Context, user models.User)
 	_, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
 		log.Println("Error while creating user:", err)
-		return models.User{}, err
+		return models.User{}, duplicateKeyError(err)
 	}
 	log.Println("User successfully created!")
 	return user, nil
@@ -40,7 +56,7 @@ func (r *userRepositoryMongo) CreateUser(ctx context.Context, user models.User)
 
 func (r *userRepositoryMongo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
 	var user models.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(emailCollation)).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return models.User{}, nil
@@ -66,7 +82,7 @@ func (r *userRepositoryMongo) GetUserByUsername(ctx context.Context, username st
 
 func (r *userRepositoryMongo) AuthenticateUser(ctx context.Context, email, password string) (models.User, error) {
 	var user models.User
-	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	err := r.collection.FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(emailCollation)).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return models.User{}, errors.New("user not found")
@@ -94,8 +110,373 @@ func (r *userRepositoryMongo) GetUserByID(ctx context.Context, userID string) (m
 	return user, nil
 }
 
-func (r *userRepositoryMongo) DeleteUser(ctx context.Context, userID string) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+// SoftDeleteUser marks an account as deleted. Deleting an already deleted
+// account reports it as not found.
+func (r *userRepositoryMongo) SoftDeleteUser(ctx context.Context, userID string, deletedAt time.Time) error {
+	filter := bson.M{"_id": userID, "deleted_at": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set": bson.M{
+			"deleted_at": deletedAt,
+			"updated_at": deletedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// RestoreUser clears the deletion marker of an account that has not been
+// anonymized yet. It reports whether the account was restored.
+func (r *userRepositoryMongo) RestoreUser(ctx context.Context, userID string) (bool, error) {
+	filter := bson.M{
+		"_id":           userID,
+		"deleted_at":    bson.M{"$exists": true},
+		"anonymized_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetUsersDeletedBefore returns up to limit deleted accounts whose personal
+// data has not been anonymized yet, oldest deletion first.
+func (r *userRepositoryMongo) GetUsersDeletedBefore(ctx context.Context, cutoff time.Time, limit int64) ([]models.User, error) {
+	filter := bson.M{
+		"deleted_at":    bson.M{"$lte": cutoff},
+		"anonymized_at": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.M{"deleted_at": 1}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// AnonymizeUser replaces the personal data of a deleted account with
+// placeholders. The document itself is kept so that orders still reference a
+// user.
+func (r *userRepositoryMongo) AnonymizeUser(ctx context.Context, userID string, anonymizedAt time.Time) error {
+	filter := bson.M{
+		"_id":           userID,
+		"deleted_at":    bson.M{"$exists": true},
+		"anonymized_at": bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"username":      "deleted-" + userID,
+			"email":         "deleted-" + userID + "@deleted.invalid",
+			"password":      "",
+			"roles":         []string{},
+			"status":        models.UserStatusDeleted,
+			"anonymized_at": anonymizedAt,
+			"updated_at":    anonymizedAt,
+		},
+		"$unset": bson.M{
+			"two_factor": "",
+			"identities": "",
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
 	return err
 }
 
+func (r *userRepositoryMongo) UpdateProfile(ctx context.Context, user models.User) error {
+	update := bson.M{
+		"$set": bson.M{
+			"username":   user.Username,
+			"email":      user.Email,
+			"status":     user.Status,
+			"updated_at": user.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, user.ID, update)
+	if err != nil {
+		return duplicateKeyError(err)
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// UpdatePassword also clears a forced password reset.
+func (r *userRepositoryMongo) UpdatePassword(ctx context.Context, userID string, hashedPassword string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"password":   hashedPassword,
+			"updated_at": time.Now(),
+		},
+		"$unset": bson.M{"password_reset_required": ""},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *userRepositoryMongo) MarkEmailVerified(ctx context.Context, userID, email string) (bool, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     models.UserStatusActive,
+			"updated_at": time.Now(),
+		},
+	}
+
+	filter := bson.M{"_id": userID, "email": email}
+	result, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetCollation(emailCollation))
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
+func (r *userRepositoryMongo) UpdateTwoFactor(ctx context.Context, userID string, twoFactor models.TwoFactor) error {
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor": twoFactor,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode removes a recovery code in a single update, so that a
+// code can only be used once even under concurrent logins.
+func (r *userRepositoryMongo) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	filter := bson.M{
+		"_id":                       userID,
+		"two_factor.enabled":        true,
+		"two_factor.recovery_codes": codeHash,
+	}
+	update := bson.M{
+		"$pull": bson.M{"two_factor.recovery_codes": codeHash},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetUserByExternalIdentity returns an empty user when no account is linked to
+// the identity, like GetUserByEmail.
+func (r *userRepositoryMongo) GetUserByExternalIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	var user models.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.User{}, nil
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (r *userRepositoryMongo) LinkExternalIdentity(ctx context.Context, userID string, identity models.ExternalIdentity) error {
+	update := bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return duplicateKeyError(err)
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// SearchUsers returns a page of users matching filter, newest first, and the
+// total number of matches.
+func (r *userRepositoryMongo) SearchUsers(ctx context.Context, filter repositories.UserFilter, skip, limit int64) ([]models.User, int64, error) {
+	query := bson.M{}
+	if filter.Email != "" {
+		query["email"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.Email), "$options": "i"}
+	}
+	if filter.Username != "" {
+		query["username"] = bson.M{"$regex": "^" + regexp.QuoteMeta(filter.Username), "$options": "i"}
+	}
+	if !filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero() {
+		createdAt := bson.M{}
+		if !filter.CreatedAfter.IsZero() {
+			createdAt["$gte"] = filter.CreatedAfter
+		}
+		if !filter.CreatedBefore.IsZero() {
+			createdAt["$lt"] = filter.CreatedBefore
+		}
+		query["created_at"] = createdAt
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *userRepositoryMongo) SetDisabled(ctx context.Context, userID string, disabled bool) error {
+	update := bson.M{
+		"$set": bson.M{
+			"disabled":   disabled,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *userRepositoryMongo) SetPasswordResetRequired(ctx context.Context, userID string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"password_reset_required": true,
+			"updated_at":              time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *userRepositoryMongo) UpdateRoles(ctx context.Context, userID string, roles []string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"roles":      roles,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, userID, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// EnsureIndexes creates the indexes used by lookups and admin searches.
+// Creating an index that already exists is a no-op. Emails and usernames are
+// unique, emails regardless of case, and so is every linked provider
+// identity; creation fails while duplicates exist. The plain "email" and
+// "username" indexes cover the same keys and are dropped first.
+func (r *userRepositoryMongo) EnsureIndexes(ctx context.Context) error {
+	for _, name := range []string{"email", "username"} {
+		if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return fmt.Errorf("dropping index %s: %w", name, err)
+		}
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_unique").SetUnique(true).SetCollation(emailCollation),
+		},
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetName("username_unique").SetUnique(true),
+		},
+		{
+			// partial, so that the many users without identities do not collide
+			Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetName("identity_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+		},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}, Options: options.Index().SetName("created_at")},
+		{
+			// for the purge of deleted accounts, which are few
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetName("deleted_at").
+				SetPartialFilterExpression(bson.M{"deleted_at": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+// isIndexNotFound reports whether an index could not be dropped because it,
+// or the whole collection, does not exist.
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)
+}
+
+// duplicateKeyError turns a unique index violation into an
+// AlreadyExistsError naming the field.
+func duplicateKeyError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	for index, field := range uniqueIndexFields {
+		if strings.Contains(err.Error(), "index: "+index+" ") {
+			return &apperrors.AlreadyExistsError{Field: field}
+		}
+	}
+	return &apperrors.AlreadyExistsError{Field: "email or username"}
+}
@@ -0,0 +1,72 @@
+//go:build integration
+// +build integration
+
+package repositories
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"testing"
+	"user-service/internal/core/models"
+)
+
+func connectTestDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	mongoURI := os.Getenv("MONGODB_URI")
+	if mongoURI == "" {
+		t.Fatal("MONGODB_URI environment variable is not set")
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	db := client.Database("test")
+	t.Cleanup(func() { db.Drop(context.Background()) })
+	return db
+}
+
+func TestMigrateOrderStatuses_Integration(t *testing.T) {
+	ctx := context.Background()
+	db := connectTestDatabase(t)
+	repo := NewOrderRepositoryMongo(db)
+
+	_, err := db.Collection("orders").InsertMany(ctx, []interface{}{
+		bson.M{"order_id": "legacy", "status": "completed"},
+		bson.M{"order_id": "history", "status": "refunded", "status_history": bson.A{
+			bson.M{"status": "pending"},
+			bson.M{"status": "completed"},
+			bson.M{"status": "refunded"},
+		}},
+		bson.M{"order_id": "current", "status": "shipped"},
+	})
+	require.NoError(t, err)
+
+	migrated, err := repo.MigrateStatuses(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), migrated)
+
+	order, err := repo.GetOrderByID(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusDelivered, order.Status)
+	assert.True(t, models.CanTransitionOrder(order.Status, models.OrderStatusRefunded))
+
+	order, err = repo.GetOrderByID(ctx, "history")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusRefunded, order.Status)
+	assert.Equal(t, models.OrderStatusDelivered, order.StatusHistory[1].Status)
+
+	order, err = repo.GetOrderByID(ctx, "current")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusShipped, order.Status)
+
+	migrated, err = repo.MigrateStatuses(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, migrated)
+}
@@ -6,14 +6,19 @@ package repositories
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"os"
 	"testing"
 	"time"
+	"user-service/internal/core/auth"
 	"user-service/internal/core/models"
 	"user-service/internal/infrastructure/cache"
+	"user-service/internal/infrastructure/cache/cachetest"
 	email2 "user-service/internal/infrastructure/email"
+	stdlogger "user-service/internal/infrastructure/logger"
 	"user-service/internal/infrastructure/utils/jwt"
 	"user-service/internal/infrastructure/utils/security"
 	"user-service/internal/infrastructure/utils/uuid"
@@ -41,18 +46,18 @@ func TestCreateUser_Integration(t *testing.T) {
 
 	passwordHash := security.NewBcryptHash()
 	userRepo := NewUserRepositoryMongo(db, passwordHash)
-	validator := validators.NewUserValidator()
+	validator := validators.NewUserValidator(validators.DefaultPasswordPolicy(), nil)
 	uuidGen := uuid.NewUUIDService()
 	email := email2.NewSMTPEmailService()
 
 	var (
 		jwtService jwt.JWTService               = nil
 		orderRepo  repositories.OrderRepository = nil
-		cache      cache.CacheService           = nil
-		logger     logger.Logger                = nil
+		cache      cache.CacheService           = cachetest.NewMemoryCache()
+		logger     logger.Logger                = &stdlogger.StdLogger{}
 	)
 
-	userService := services.NewUserService(userRepo, validator, passwordHash, jwtService, uuidGen, client, orderRepo, cache, logger, email)
+	userService := services.NewUserService(userRepo, validator, passwordHash, jwtService, uuidGen, orderRepo, cache, logger, email, auth.EmailVerificationOptional, 30*24*time.Hour, nil)
 
 	user := models.User{
 		Username: "arsen",
@@ -89,3 +94,34 @@ func TestCreateUser_Integration(t *testing.T) {
 	_, err = userService.RegisterUser(ctx, invalidUser)
 	assert.Error(t, err)
 }
+
+func TestEnsureUserIndexes_Integration(t *testing.T) {
+	ctx := context.Background()
+	db := connectTestDatabase(t)
+	repo := NewUserRepositoryMongo(db, security.NewBcryptHash())
+
+	t.Run("Replaces the plain email and username indexes", func(t *testing.T) {
+		_, err := db.Collection("users").Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email")},
+			{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetName("username")},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.EnsureIndexes(ctx))
+
+		specs, err := db.Collection("users").Indexes().ListSpecifications(ctx)
+		require.NoError(t, err)
+		names := []string{}
+		for _, spec := range specs {
+			names = append(names, spec.Name)
+		}
+		assert.Contains(t, names, "email_unique")
+		assert.Contains(t, names, "username_unique")
+		assert.NotContains(t, names, "email")
+		assert.NotContains(t, names, "username")
+	})
+
+	t.Run("Is repeatable", func(t *testing.T) {
+		require.NoError(t, repo.EnsureIndexes(ctx))
+	})
+}
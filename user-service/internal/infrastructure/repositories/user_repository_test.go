@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	driver "go.mongodb.org/mongo-driver/mongo"
 )
 
 type mockCollection struct {
@@ -87,3 +88,10 @@ func TestGetUserByEmail(t *testing.T) {
 	})
 
 }
+
+func TestIsIndexNotFound(t *testing.T) {
+	assert.True(t, isIndexNotFound(driver.CommandError{Code: 27, Name: "IndexNotFound"}))
+	assert.True(t, isIndexNotFound(driver.CommandError{Code: 26, Name: "NamespaceNotFound"}))
+	assert.False(t, isIndexNotFound(driver.CommandError{Code: 85, Name: "IndexOptionsConflict"}))
+	assert.False(t, isIndexNotFound(errors.New("connection reset")))
+}
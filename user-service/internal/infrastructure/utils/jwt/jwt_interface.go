@@ -3,12 +3,52 @@ package jwt
 import (
 	"context"
 	"time"
+	"user-service/internal/core/models"
 )
 
+// TokenPair is issued at login and on refresh. SessionID identifies the refresh
+// token family and TokenID the access token's jti.
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        time.Time
+	SessionID        string
+	TokenID          string
+	RefreshExpiresAt time.Time
+}
+
+// Identity is the part of a user that is embedded into access tokens.
+type Identity struct {
+	UserID        string
+	Roles         []string
+	EmailVerified bool
+}
+
+func NewIdentity(user models.User) Identity {
+	return Identity{
+		UserID:        user.ID,
+		Roles:         user.Roles,
+		EmailVerified: user.IsEmailVerified(),
+	}
+}
+
+type Claims struct {
+	Identity
+	SessionID string
+}
+
+// IdentityLookup resolves the current identity of a user when a refresh token
+// is exchanged.
+type IdentityLookup func(userID string) (Identity, error)
+
 type JWTService interface {
-	GenerateJWT(userID string) (string, error)
-	VerifyToken(token string) (string, error)
+	GenerateJWT(identity Identity) (string, error)
+	GenerateTokenPair(identity Identity) (*TokenPair, error)
+	RefreshToken(refreshToken string, lookup IdentityLookup) (*TokenPair, error)
+	VerifyToken(token string) (*Claims, error)
 	BlacklistToken(token string, ttl time.Duration) error
 	InvalidateToken(tokenString string) error
+	RevokeSession(sessionID string) error
+	RevokeAllTokens(userID string) error
 	ExtractTokenFromContext(ctx context.Context) (string, error)
 }
@@ -2,77 +2,164 @@ package jwt
 
 import (
 	"context"
-	"user-service/internal/errors"
-	"user-service/internal/infrastructure/cache"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"strconv"
 	"strings"
 	"time"
+	"user-service/internal/errors"
+	"user-service/internal/infrastructure/cache"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+
+	refreshFamilyPrefix   = "refresh_family:"
+	tokenGenerationPrefix = "token_generation:"
 )
 
 type service struct {
-	secretKey string
-	cache cache.CacheService
+	keys         *KeySet
+	legacySecret string
+	cache        cache.CacheService
 }
 
-func NewJWTService(secretKey string, cache cache.CacheService) *service {
-	return &service{secretKey: secretKey, cache: cache}
+// NewJWTService signs tokens with the active key of keys. When legacySecret is
+// set, HS256 tokens issued before the switch to asymmetric keys still verify.
+func NewJWTService(keys *KeySet, legacySecret string, cache cache.CacheService) *service {
+	return &service{keys: keys, legacySecret: legacySecret, cache: cache}
 }
 
-func (s *service) GenerateJWT(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.secretKey))
+func (s *service) GenerateJWT(identity Identity) (string, error) {
+	generation, err := s.tokenGeneration(identity.UserID)
 	if err != nil {
-		return "", errors.ErrJWTGeneration
+		return "", err
 	}
-	return tokenString, nil
+	return s.sign(s.accessClaims(identity, "", generation, time.Now().Add(AccessTokenTTL)))
 }
 
-func (s *service) VerifyToken(tokenString string) (string, error) {
+func (s *service) GenerateTokenPair(identity Identity) (*TokenPair, error) {
+	return s.issueTokenPair(identity, uuid.NewString(), "")
+}
 
-	if ok, err := s.cache.Exists(tokenString); err == nil && ok {
-		return "", errors.ErrInvalidToken
+// RefreshToken rotates a refresh token. Every refresh token belongs to a family
+// started at login, and only the most recently issued token of a family is
+// accepted; presenting an older one revokes the whole family. The family is
+// moved to the new token with a compare-and-swap, so of several concurrent
+// refreshes with the same token only one succeeds and the others count as
+// reuse. The new access token is built from the identity returned by lookup,
+// so role and verification changes are picked up on refresh.
+func (s *service) RefreshToken(refreshToken string, lookup IdentityLookup) (*TokenPair, error) {
+	claims, err := s.parse(refreshToken)
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.ErrInvalidToken
+	if claims["type"] != tokenTypeRefresh {
+		return nil, errors.ErrInvalidToken
+	}
+
+	userID, _ := claims["user_id"].(string)
+	tokenID, _ := claims["jti"].(string)
+	familyID, _ := claims["sid"].(string)
+	if userID == "" || tokenID == "" || familyID == "" {
+		return nil, errors.ErrInvalidToken
+	}
+
+	if err := s.checkGeneration(userID, claims); err != nil {
+		return nil, err
+	}
+
+	familyKey := refreshFamilyPrefix + familyID
+
+	currentID, err := s.cache.Get(familyKey)
+	if err == cache.ErrCacheMiss || (err == nil && currentID == "") {
+		return nil, errors.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, errors.ErrTokenRevocationCheck
+	}
+
+	if currentID != tokenID {
+		if err := s.cache.Delete(familyKey); err != nil {
+			return nil, err
 		}
-		return []byte(s.secretKey), nil
-	})
+		return nil, &errors.RefreshTokenReuseError{UserID: userID, SessionID: familyID}
+	}
 
-	if err != nil || !token.Valid {
-		return "", errors.ErrInvalidToken
+	identity, err := lookup(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", errors.ErrInvalidToken
+	return s.issueTokenPair(identity, familyID, tokenID)
+}
+
+// VerifyToken rejects tokens whose revocation status cannot be read from the
+// cache with ErrTokenRevocationCheck rather than letting them through.
+func (s *service) VerifyToken(tokenString string) (*Claims, error) {
+	blacklisted, err := s.cache.Exists(tokenString)
+	if err != nil {
+		return nil, errors.ErrTokenRevocationCheck
+	}
+	if blacklisted {
+		return nil, errors.ErrInvalidToken
+	}
+
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims["type"] == tokenTypeRefresh {
+		return nil, errors.ErrInvalidToken
+	}
+
+	familyID, _ := claims["sid"].(string)
+	if familyID != "" {
+		active, err := s.cache.Exists(refreshFamilyPrefix + familyID)
+		if err != nil {
+			return nil, errors.ErrTokenRevocationCheck
+		}
+		if !active {
+			return nil, errors.ErrInvalidToken
+		}
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		return "", errors.ErrInvalidToken
+		return nil, errors.ErrInvalidToken
+	}
+
+	if err := s.checkGeneration(userID, claims); err != nil {
+		return nil, err
 	}
 
-	return userID, nil
+	emailVerified, _ := claims["email_verified"].(bool)
+
+	return &Claims{
+		Identity: Identity{
+			UserID:        userID,
+			Roles:         rolesFromClaims(claims),
+			EmailVerified: emailVerified,
+		},
+		SessionID: familyID,
+	}, nil
 }
 
 func (s *service) InvalidateToken(tokenString string) error {
-	parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.secretKey), nil
-	})
-	if err != nil || !parsedToken.Valid {
-		return errors.ErrInvalidToken
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return err
 	}
 
-	claims := parsedToken.Claims.(jwt.MapClaims)
 	exp, ok := claims["exp"].(float64)
 	if !ok {
 		return errors.ErrInvalidToken
@@ -84,6 +171,18 @@ func (s *service) InvalidateToken(tokenString string) error {
 	return s.BlacklistToken(tokenString, ttl)
 }
 
+func (s *service) RevokeSession(sessionID string) error {
+	return s.cache.Delete(refreshFamilyPrefix + sessionID)
+}
+
+// RevokeAllTokens bumps the user's token generation. Every token carries the
+// generation it was issued under, so all previously issued access and refresh
+// tokens stop verifying at once.
+func (s *service) RevokeAllTokens(userID string) error {
+	_, err := s.cache.Increment(tokenGenerationPrefix + userID)
+	return err
+}
+
 func (s *service) BlacklistToken(tokenString string, ttl time.Duration) error {
 	return s.cache.Set(tokenString, "blacklisted", ttl)
 }
@@ -106,3 +205,171 @@ func (s *service) ExtractTokenFromContext(ctx context.Context) (string, error) {
 
 	return tokenParts[1], nil
 }
+
+// issueTokenPair starts the family familyID, or, when rotatedID is set, moves
+// it from the refresh token rotatedID to the new one.
+func (s *service) issueTokenPair(identity Identity, familyID, rotatedID string) (*TokenPair, error) {
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenTTL)
+	generation, err := s.tokenGeneration(identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := s.accessClaims(identity, familyID, generation, expiresAt)
+	accessToken, err := s.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshID := uuid.NewString()
+	refreshExpiresAt := now.Add(RefreshTokenTTL)
+	refreshToken, err := s.sign(jwt.MapClaims{
+		"user_id": identity.UserID,
+		"type":    tokenTypeRefresh,
+		"jti":     refreshID,
+		"sid":     familyID,
+		"gen":     generation,
+		"exp":     refreshExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	familyKey := refreshFamilyPrefix + familyID
+	if rotatedID == "" {
+		if err := s.cache.Set(familyKey, refreshID, RefreshTokenTTL); err != nil {
+			return nil, err
+		}
+	} else {
+		swapped, err := s.cache.CompareAndSwap(familyKey, rotatedID, refreshID, RefreshTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		if !swapped {
+			if err := s.cache.Delete(familyKey); err != nil {
+				return nil, err
+			}
+			return nil, &errors.RefreshTokenReuseError{UserID: identity.UserID, SessionID: familyID}
+		}
+	}
+
+	tokenID, _ := claims["jti"].(string)
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresAt:        expiresAt,
+		SessionID:        familyID,
+		TokenID:          tokenID,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+func (s *service) accessClaims(identity Identity, familyID string, generation int64, expiresAt time.Time) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"user_id":        identity.UserID,
+		"roles":          identity.Roles,
+		"email_verified": identity.EmailVerified,
+		"type":           tokenTypeAccess,
+		"jti":            uuid.NewString(),
+		"gen":            generation,
+		"exp":            expiresAt.Unix(),
+	}
+	if familyID != "" {
+		claims["sid"] = familyID
+	}
+	return claims
+}
+
+func (s *service) sign(claims jwt.MapClaims) (string, error) {
+	key := s.keys.activeKey()
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.id
+
+	tokenString, err := token.SignedString(key.privateKey)
+	if err != nil {
+		return "", errors.ErrJWTGeneration
+	}
+	return tokenString, nil
+}
+
+func (s *service) parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.verificationKey)
+
+	if err != nil || !token.Valid {
+		return nil, errors.ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// tokenGeneration returns the user's current token generation, 0 if their
+// tokens were never revoked. Any other failure is reported as
+// ErrTokenRevocationCheck, since a wrong generation would revive revoked
+// tokens.
+func (s *service) tokenGeneration(userID string) (int64, error) {
+	value, err := s.cache.Get(tokenGenerationPrefix + userID)
+	if err == cache.ErrCacheMiss {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.ErrTokenRevocationCheck
+	}
+
+	generation, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.ErrTokenRevocationCheck
+	}
+	return generation, nil
+}
+
+// checkGeneration rejects tokens issued before the user's tokens were last
+// revoked.
+func (s *service) checkGeneration(userID string, claims jwt.MapClaims) error {
+	current, err := s.tokenGeneration(userID)
+	if err != nil {
+		return err
+	}
+
+	generation, _ := claims["gen"].(float64)
+	if int64(generation) < current {
+		return errors.ErrInvalidToken
+	}
+	return nil
+}
+
+func rolesFromClaims(claims jwt.MapClaims) []string {
+	values, _ := claims["roles"].([]interface{})
+
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if role, ok := value.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func (s *service) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		if _, isHMAC := token.Method.(*jwt.SigningMethodHMAC); isHMAC && s.legacySecret != "" {
+			return []byte(s.legacySecret), nil
+		}
+		return nil, errors.ErrInvalidToken
+	}
+
+	key, ok := s.keys.lookup(kid)
+	if !ok || token.Method.Alg() != key.algorithm {
+		return nil, errors.ErrInvalidToken
+	}
+
+	return key.privateKey.Public(), nil
+}
@@ -0,0 +1,221 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	apperrors "user-service/internal/errors"
+	"user-service/internal/infrastructure/cache/cachetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, algorithm string) *service {
+	keys, err := NewKeySet(algorithm, "")
+	assert.NoError(t, err)
+	return NewJWTService(keys, "", cachetest.NewMemoryCache())
+}
+
+// unavailableCache fails reads once down is set, like a Redis outage.
+type unavailableCache struct {
+	*cachetest.MemoryCache
+	down bool
+}
+
+func (c *unavailableCache) Get(key string) (string, error) {
+	if c.down {
+		return "", errors.New("connection refused")
+	}
+	return c.MemoryCache.Get(key)
+}
+
+func (c *unavailableCache) Exists(key string) (bool, error) {
+	if c.down {
+		return false, errors.New("connection refused")
+	}
+	return c.MemoryCache.Exists(key)
+}
+
+func lookupIdentity(userID string) (Identity, error) {
+	return Identity{UserID: userID, Roles: []string{"customer"}, EmailVerified: true}, nil
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Run("Rotates refresh token", func(t *testing.T) {
+		svc := newTestService(t, AlgorithmRS256)
+
+		pair, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+		assert.NoError(t, err)
+
+		rotated, err := svc.RefreshToken(pair.RefreshToken, lookupIdentity)
+		assert.NoError(t, err)
+		assert.NotEqual(t, pair.RefreshToken, rotated.RefreshToken)
+
+		claims, err := svc.VerifyToken(rotated.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", claims.UserID)
+		assert.Equal(t, []string{"customer"}, claims.Roles)
+		assert.True(t, claims.EmailVerified)
+	})
+
+	t.Run("Reuse revokes the family", func(t *testing.T) {
+		svc := newTestService(t, AlgorithmRS256)
+
+		pair, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+		assert.NoError(t, err)
+
+		rotated, err := svc.RefreshToken(pair.RefreshToken, lookupIdentity)
+		assert.NoError(t, err)
+
+		_, err = svc.RefreshToken(pair.RefreshToken, lookupIdentity)
+		assert.ErrorIs(t, err, apperrors.ErrRefreshTokenReused)
+		assert.Equal(t, &apperrors.RefreshTokenReuseError{UserID: "user-1", SessionID: pair.SessionID}, err)
+
+		_, err = svc.RefreshToken(rotated.RefreshToken, lookupIdentity)
+		assert.Equal(t, apperrors.ErrInvalidToken, err)
+
+		_, err = svc.VerifyToken(rotated.AccessToken)
+		assert.Equal(t, apperrors.ErrInvalidToken, err)
+	})
+
+	t.Run("Concurrent refreshes with the same token succeed once", func(t *testing.T) {
+		svc := newTestService(t, AlgorithmEdDSA)
+
+		pair, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+		require.NoError(t, err)
+
+		const attempts = 20
+		var wg sync.WaitGroup
+		errs := make(chan error, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.RefreshToken(pair.RefreshToken, lookupIdentity)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			} else if err != apperrors.ErrInvalidToken {
+				assert.ErrorIs(t, err, apperrors.ErrRefreshTokenReused)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("Access token is not a refresh token", func(t *testing.T) {
+		svc := newTestService(t, AlgorithmRS256)
+
+		pair, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+		assert.NoError(t, err)
+
+		_, err = svc.RefreshToken(pair.AccessToken, lookupIdentity)
+		assert.Equal(t, apperrors.ErrInvalidToken, err)
+
+		_, err = svc.VerifyToken(pair.RefreshToken)
+		assert.Equal(t, apperrors.ErrInvalidToken, err)
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			svc := newTestService(t, algorithm)
+
+			before, err := svc.GenerateJWT(Identity{UserID: "user-1", Roles: []string{"customer"}})
+			assert.NoError(t, err)
+
+			assert.NoError(t, svc.keys.Rotate())
+
+			after, err := svc.GenerateJWT(Identity{UserID: "user-1", Roles: []string{"customer"}})
+			assert.NoError(t, err)
+
+			for _, token := range []string{before, after} {
+				claims, err := svc.VerifyToken(token)
+				assert.NoError(t, err)
+				assert.Equal(t, "user-1", claims.UserID)
+			}
+
+			jwks, err := svc.keys.JWKS()
+			assert.NoError(t, err)
+
+			var doc struct {
+				Keys []map[string]string `json:"keys"`
+			}
+			assert.NoError(t, json.Unmarshal(jwks, &doc))
+			assert.Len(t, doc.Keys, 2)
+			assert.Equal(t, algorithm, doc.Keys[1]["alg"])
+		})
+	}
+}
+
+func TestKeySetPersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	keys, err := NewKeySet(AlgorithmEdDSA, dir)
+	assert.NoError(t, err)
+	token, err := NewJWTService(keys, "", cachetest.NewMemoryCache()).GenerateJWT(Identity{UserID: "user-1", Roles: []string{"customer"}})
+	assert.NoError(t, err)
+
+	reloaded, err := NewKeySet(AlgorithmEdDSA, dir)
+	assert.NoError(t, err)
+	claims, err := NewJWTService(reloaded, "", cachetest.NewMemoryCache()).VerifyToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+	svc := newTestService(t, AlgorithmRS256)
+
+	pair, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+	assert.NoError(t, err)
+	other, err := svc.GenerateTokenPair(Identity{UserID: "user-2", Roles: []string{"customer"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, svc.RevokeAllTokens("user-1"))
+
+	_, err = svc.VerifyToken(pair.AccessToken)
+	assert.Equal(t, apperrors.ErrInvalidToken, err)
+	_, err = svc.RefreshToken(pair.RefreshToken, lookupIdentity)
+	assert.Equal(t, apperrors.ErrInvalidToken, err)
+
+	_, err = svc.VerifyToken(other.AccessToken)
+	assert.NoError(t, err)
+
+	fresh, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+	assert.NoError(t, err)
+	_, err = svc.VerifyToken(fresh.AccessToken)
+	assert.NoError(t, err)
+}
+
+func TestVerifyTokenFailsClosed(t *testing.T) {
+	keys, err := NewKeySet(AlgorithmEdDSA, "")
+	require.NoError(t, err)
+	cache := &unavailableCache{MemoryCache: cachetest.NewMemoryCache()}
+	svc := NewJWTService(keys, "", cache)
+
+	pair, err := svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+	require.NoError(t, err)
+
+	cache.down = true
+
+	_, err = svc.VerifyToken(pair.AccessToken)
+	assert.Equal(t, apperrors.ErrTokenRevocationCheck, err)
+	_, err = svc.RefreshToken(pair.RefreshToken, lookupIdentity)
+	assert.Equal(t, apperrors.ErrTokenRevocationCheck, err)
+	_, err = svc.GenerateTokenPair(Identity{UserID: "user-1", Roles: []string{"customer"}})
+	assert.Equal(t, apperrors.ErrTokenRevocationCheck, err)
+
+	cache.down = false
+
+	_, err = svc.VerifyToken(pair.AccessToken)
+	assert.NoError(t, err)
+}
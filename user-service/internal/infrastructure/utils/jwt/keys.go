@@ -0,0 +1,375 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits            = 2048
+	rotationCheckInterval = time.Minute
+	reloadCooldown        = 10 * time.Second
+)
+
+type signingKey struct {
+	id         string
+	algorithm  string
+	privateKey crypto.Signer
+	createdAt  time.Time
+	retiredAt  time.Time
+}
+
+func (k *signingKey) method() jwt.SigningMethod {
+	if k.algorithm == AlgorithmEdDSA {
+		return SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeySet holds the asymmetric keys used to sign tokens. The newest key signs,
+// older keys are retired but keep verifying until every token they could have
+// signed has expired. When dir is set, keys are persisted there as PKCS#8 PEM
+// files named after their kid so that restarts and replicas share them.
+type KeySet struct {
+	mu         sync.RWMutex
+	algorithm  string
+	dir        string
+	keys       []*signingKey
+	lastReload time.Time
+}
+
+func NewKeySet(algorithm, dir string) (*KeySet, error) {
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	k := &KeySet{algorithm: algorithm, dir: dir}
+
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	if len(k.keys) == 0 {
+		if err := k.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+func (k *KeySet) Reload() error {
+	if k.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(k.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(k.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read key directory: %w", err)
+	}
+
+	var keys []*signingKey
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+
+		key, err := loadKey(filepath.Join(k.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(keys) > 0 {
+		k.keys = retireKeys(keys)
+	}
+	k.lastReload = time.Now()
+
+	return nil
+}
+
+func (k *KeySet) Rotate() error {
+	key, err := generateKey(k.algorithm)
+	if err != nil {
+		return err
+	}
+
+	if k.dir != "" {
+		if err := saveKey(k.dir, key); err != nil {
+			return err
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = retireKeys(append(k.keys, key))
+	log.Printf("Rotated JWT signing key, active kid: %s", key.id)
+
+	return nil
+}
+
+// Prune drops keys that were retired long enough ago that no token signed by
+// them can still be valid.
+func (k *KeySet) Prune() {
+	cutoff := time.Now().Add(-RefreshTokenTTL)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	kept := make([]*signingKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.retiredAt.IsZero() && key.retiredAt.Before(cutoff) {
+			if k.dir != "" {
+				if err := os.Remove(filepath.Join(k.dir, key.id+".pem")); err != nil && !os.IsNotExist(err) {
+					log.Printf("Failed to remove expired signing key %s: %v", key.id, err)
+				}
+			}
+			continue
+		}
+		kept = append(kept, key)
+	}
+	k.keys = kept
+}
+
+// StartRotation blocks and rotates the active key once it is older than
+// interval. It also picks up keys rotated by other replicas.
+func (k *KeySet) StartRotation(interval time.Duration) {
+	ticker := time.NewTicker(rotationCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := k.Reload(); err != nil {
+			log.Printf("Failed to reload JWT signing keys: %v", err)
+			continue
+		}
+
+		if time.Since(k.activeKey().createdAt) >= interval {
+			if err := k.Rotate(); err != nil {
+				log.Printf("Failed to rotate JWT signing key: %v", err)
+			}
+		}
+
+		k.Prune()
+	}
+}
+
+func (k *KeySet) JWKS() ([]byte, error) {
+	type jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]jwk, 0, len(k.keys))
+	for _, key := range k.keys {
+		entry := jwk{Kid: key.id, Use: "sig", Alg: key.algorithm}
+
+		switch publicKey := key.privateKey.Public().(type) {
+		case *rsa.PublicKey:
+			entry.Kty = "RSA"
+			entry.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			entry.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			entry.Kty = "OKP"
+			entry.Crv = "Ed25519"
+			entry.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			return nil, fmt.Errorf("unsupported key type for kid %s", key.id)
+		}
+
+		keys = append(keys, entry)
+	}
+
+	return json.Marshal(map[string][]jwk{"keys": keys})
+}
+
+func (k *KeySet) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := k.JWKS()
+		if err != nil {
+			log.Printf("Failed to build JWKS: %v", err)
+			http.Error(w, "failed to build JWKS", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(body)
+	})
+}
+
+func (k *KeySet) activeKey() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[len(k.keys)-1]
+}
+
+func (k *KeySet) lookup(kid string) (*signingKey, bool) {
+	if key, ok := k.find(kid); ok {
+		return key, true
+	}
+
+	k.mu.RLock()
+	canReload := k.dir != "" && time.Since(k.lastReload) > reloadCooldown
+	k.mu.RUnlock()
+
+	if !canReload {
+		return nil, false
+	}
+
+	if err := k.Reload(); err != nil {
+		log.Printf("Failed to reload JWT signing keys: %v", err)
+		return nil, false
+	}
+
+	return k.find(kid)
+}
+
+func (k *KeySet) find(kid string) (*signingKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.id == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func retireKeys(keys []*signingKey) []*signingKey {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	for i := 0; i < len(keys)-1; i++ {
+		keys[i].retiredAt = keys[i+1].createdAt
+	}
+	keys[len(keys)-1].retiredAt = time.Time{}
+
+	return keys
+}
+
+func generateKey(algorithm string) (*signingKey, error) {
+	var privateKey crypto.Signer
+
+	switch algorithm {
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		privateKey = key
+	default:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		privateKey = key
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now()
+
+	return &signingKey{
+		id:         fmt.Sprintf("%d-%s", createdAt.Unix(), hex.EncodeToString(suffix)),
+		algorithm:  algorithm,
+		privateKey: privateKey,
+		createdAt:  createdAt,
+	}, nil
+}
+
+func saveKey(dir string, key *signingKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, key.id+".pem"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	return nil
+}
+
+func loadKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+
+	key := &signingKey{id: strings.TrimSuffix(filepath.Base(path), ".pem")}
+
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.algorithm = AlgorithmRS256
+		key.privateKey = privateKey
+	case ed25519.PrivateKey:
+		key.algorithm = AlgorithmEdDSA
+		key.privateKey = privateKey
+	default:
+		return nil, fmt.Errorf("signing key %s has unsupported type %T", path, parsed)
+	}
+
+	createdAt, _, _ := strings.Cut(key.id, "-")
+	if unix, err := strconv.ParseInt(createdAt, 10, 64); err == nil {
+		key.createdAt = time.Unix(unix, 0)
+	} else if info, err := os.Stat(path); err == nil {
+		key.createdAt = info.ModTime()
+	}
+
+	return key, nil
+}
@@ -1,18 +1,27 @@
 package security
 
 import (
-	"user-service/internal/errors"
 	"golang.org/x/crypto/bcrypt"
+	"user-service/internal/errors"
 )
 
-type BcryptHash struct{}
+type BcryptHash struct {
+	cost int
+}
 
 func NewBcryptHash() *BcryptHash {
-	return &BcryptHash{}
+	return NewBcryptHashWithCost(bcrypt.DefaultCost)
+}
+
+func NewBcryptHashWithCost(cost int) *BcryptHash {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHash{cost: cost}
 }
 
 func (h *BcryptHash) HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
 	if err != nil {
 		return "", errors.ErrPasswordHashing
 	}
@@ -23,3 +32,8 @@ func (h *BcryptHash) CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+func (h *BcryptHash) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
@@ -3,4 +3,7 @@ package security
 type PasswordHash interface {
 	HashPassword(password string) (string, error)
 	CheckPasswordHash(password, hash string) bool
+	// NeedsRehash reports whether a hash was produced by another algorithm or
+	// with other parameters than the ones currently configured.
+	NeedsRehash(hash string) bool
 }
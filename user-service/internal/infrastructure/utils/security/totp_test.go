@@ -0,0 +1,64 @@
+package security
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 appendix B, SHA1 variant.
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for unix, expected := range vectors {
+		assert.Equal(t, expected, totpCode(key, unix/30, 8), "time %d", unix)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	at := time.Unix(1111111109, 0)
+
+	t.Run("Accepts current code", func(t *testing.T) {
+		step, ok := VerifyTOTP(secret, "081804", at)
+		assert.True(t, ok)
+		assert.Equal(t, int64(1111111109/30), step)
+	})
+
+	t.Run("Accepts one step of clock skew", func(t *testing.T) {
+		_, ok := VerifyTOTP(secret, "081804", at.Add(TOTPPeriod))
+		assert.True(t, ok)
+
+		_, ok = VerifyTOTP(secret, "081804", at.Add(3*TOTPPeriod))
+		assert.False(t, ok)
+	})
+
+	t.Run("Rejects malformed input", func(t *testing.T) {
+		_, ok := VerifyTOTP(secret, "81804", at)
+		assert.False(t, ok)
+
+		_, ok = VerifyTOTP("not base32!", "081804", at)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	uri := TOTPProvisioningURI("ecommerce", "user@example.com", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/ecommerce:user@example.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+}
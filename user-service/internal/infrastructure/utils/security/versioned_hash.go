@@ -0,0 +1,63 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// VersionedHash hashes new passwords with the configured algorithm and
+// verifies hashes of every supported algorithm, recognised by their prefix.
+// Hashes in another format or with outdated parameters need a rehash.
+type VersionedHash struct {
+	algorithm string
+	hashers   map[string]PasswordHash
+}
+
+func NewVersionedHash(algorithm string, argon2Params Argon2Params, bcryptCost int) (*VersionedHash, error) {
+	if algorithm != AlgorithmArgon2id && algorithm != AlgorithmBcrypt {
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", algorithm)
+	}
+
+	return &VersionedHash{
+		algorithm: algorithm,
+		hashers: map[string]PasswordHash{
+			AlgorithmArgon2id: NewArgon2idHash(argon2Params),
+			AlgorithmBcrypt:   NewBcryptHashWithCost(bcryptCost),
+		},
+	}, nil
+}
+
+func (h *VersionedHash) HashPassword(password string) (string, error) {
+	return h.hashers[h.algorithm].HashPassword(password)
+}
+
+func (h *VersionedHash) CheckPasswordHash(password, hash string) bool {
+	hasher, ok := h.hashers[hashAlgorithm(hash)]
+	if !ok {
+		return false
+	}
+	return hasher.CheckPasswordHash(password, hash)
+}
+
+func (h *VersionedHash) NeedsRehash(hash string) bool {
+	if hashAlgorithm(hash) != h.algorithm {
+		return true
+	}
+	return h.hashers[h.algorithm].NeedsRehash(hash)
+}
+
+func hashAlgorithm(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
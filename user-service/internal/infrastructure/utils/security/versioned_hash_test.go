@@ -0,0 +1,64 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testArgon2Params() Argon2Params {
+	return Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestVersionedHash(t *testing.T) {
+	t.Run("Verifies both formats", func(t *testing.T) {
+		hasher, err := NewVersionedHash(AlgorithmArgon2id, testArgon2Params(), 4)
+		assert.NoError(t, err)
+
+		argonHash, err := hasher.HashPassword("Secret123")
+		assert.NoError(t, err)
+		assert.Contains(t, argonHash, "$argon2id$v=19$m=1024,t=1,p=1$")
+
+		bcryptHash, err := NewBcryptHashWithCost(4).HashPassword("Secret123")
+		assert.NoError(t, err)
+
+		assert.True(t, hasher.CheckPasswordHash("Secret123", argonHash))
+		assert.True(t, hasher.CheckPasswordHash("Secret123", bcryptHash))
+		assert.False(t, hasher.CheckPasswordHash("Wrong123", argonHash))
+		assert.False(t, hasher.CheckPasswordHash("Wrong123", bcryptHash))
+		assert.False(t, hasher.CheckPasswordHash("Secret123", "plaintext"))
+	})
+
+	t.Run("Flags other algorithms and outdated parameters", func(t *testing.T) {
+		hasher, err := NewVersionedHash(AlgorithmArgon2id, testArgon2Params(), 4)
+		assert.NoError(t, err)
+
+		current, _ := hasher.HashPassword("Secret123")
+		assert.False(t, hasher.NeedsRehash(current))
+
+		bcryptHash, _ := NewBcryptHashWithCost(4).HashPassword("Secret123")
+		assert.True(t, hasher.NeedsRehash(bcryptHash))
+
+		upgraded := testArgon2Params()
+		upgraded.Iterations = 2
+		upgradedHasher, err := NewVersionedHash(AlgorithmArgon2id, upgraded, 4)
+		assert.NoError(t, err)
+		assert.True(t, upgradedHasher.NeedsRehash(current))
+	})
+
+	t.Run("Bcrypt cost changes require rehash", func(t *testing.T) {
+		hasher, err := NewVersionedHash(AlgorithmBcrypt, testArgon2Params(), 5)
+		assert.NoError(t, err)
+
+		old, _ := NewBcryptHashWithCost(4).HashPassword("Secret123")
+		assert.True(t, hasher.NeedsRehash(old))
+
+		current, _ := hasher.HashPassword("Secret123")
+		assert.False(t, hasher.NeedsRehash(current))
+	})
+
+	t.Run("Rejects unknown algorithm", func(t *testing.T) {
+		_, err := NewVersionedHash("md5", testArgon2Params(), 4)
+		assert.Error(t, err)
+	})
+}
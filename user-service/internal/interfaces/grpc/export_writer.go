@@ -0,0 +1,30 @@
+package services
+
+import userpb "proto/generated/ecommerce/user"
+
+// exportChunkSize keeps every message well below the default 4 MiB gRPC limit.
+const exportChunkSize = 64 * 1024
+
+// exportChunkWriter sends everything written to it as ExportUserData
+// messages of at most exportChunkSize bytes.
+type exportChunkWriter struct {
+	stream userpb.UserService_ExportUserDataServer
+}
+
+func (w *exportChunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := written + exportChunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		chunk := make([]byte, end-written)
+		copy(chunk, p[written:end])
+		if err := w.stream.Send(&userpb.ExportUserDataResponse{Chunk: chunk}); err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	userpb "proto/generated/ecommerce/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// recordingExportStream keeps the chunks sent on it and fails once failAfter
+// chunks were sent, unless failAfter is zero.
+type recordingExportStream struct {
+	grpc.ServerStream
+	chunks    [][]byte
+	failAfter int
+}
+
+func (s *recordingExportStream) Send(resp *userpb.ExportUserDataResponse) error {
+	if s.failAfter > 0 && len(s.chunks) == s.failAfter {
+		return errors.New("stream closed")
+	}
+	s.chunks = append(s.chunks, resp.GetChunk())
+	return nil
+}
+
+func (s *recordingExportStream) joined() []byte {
+	return bytes.Join(s.chunks, nil)
+}
+
+func exportData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestExportChunkWriter(t *testing.T) {
+	t.Run("Splits large writes into chunks", func(t *testing.T) {
+		stream := &recordingExportStream{}
+		data := exportData(2*exportChunkSize + 100)
+
+		n, err := (&exportChunkWriter{stream: stream}).Write(data)
+		require.NoError(t, err)
+		assert.Equal(t, len(data), n)
+
+		require.Len(t, stream.chunks, 3)
+		assert.Len(t, stream.chunks[0], exportChunkSize)
+		assert.Len(t, stream.chunks[1], exportChunkSize)
+		assert.Len(t, stream.chunks[2], 100)
+		assert.Equal(t, data, stream.joined())
+	})
+
+	t.Run("Buffers small writes into full chunks", func(t *testing.T) {
+		stream := &recordingExportStream{}
+		data := exportData(3*exportChunkSize + 10)
+
+		w := bufio.NewWriterSize(&exportChunkWriter{stream: stream}, exportChunkSize)
+		for i := 0; i < len(data); i += 1000 {
+			end := i + 1000
+			if end > len(data) {
+				end = len(data)
+			}
+			_, err := w.Write(data[i:end])
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Flush())
+
+		require.Len(t, stream.chunks, 4)
+		for _, chunk := range stream.chunks[:3] {
+			assert.Len(t, chunk, exportChunkSize)
+		}
+		assert.Equal(t, data, stream.joined())
+	})
+
+	t.Run("Reports what was sent before a failure", func(t *testing.T) {
+		stream := &recordingExportStream{failAfter: 1}
+
+		n, err := (&exportChunkWriter{stream: stream}).Write(exportData(2 * exportChunkSize))
+		assert.Error(t, err)
+		assert.Equal(t, exportChunkSize, n)
+	})
+}
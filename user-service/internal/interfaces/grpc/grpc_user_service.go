@@ -1,36 +1,51 @@
 package services
 
 import (
+	"bufio"
 	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"log"
 	userpb "proto/generated/ecommerce/user"
 	"time"
+	"user-service/internal/core/auth"
 	"user-service/internal/core/models"
+	"user-service/internal/errors"
 	"user-service/internal/infrastructure/cache"
 	"user-service/internal/infrastructure/utils/jwt"
 	logger "user-service/internal/interfaces/logger"
+	"user-service/internal/interfaces/repositories"
 	"user-service/internal/usecases/services"
 )
 
 type UserGrpcServer struct {
 	userpb.UnimplementedUserServiceServer
-	userService *services.UserService
-	tokenGen    jwt.JWTService
-	logger      logger.Logger
-	cache       cache.CacheService
+	userService      *services.UserService
+	apiKeyService    *services.APIKeyService
+	oidcLoginService *services.OIDCLoginService
+	auditLog         *services.AuditLog
+	tokenGen         jwt.JWTService
+	logger           logger.Logger
+	cache            cache.CacheService
 }
 
 func NewUserGrpcServer(
 	userService *services.UserService,
+	apiKeyService *services.APIKeyService,
+	oidcLoginService *services.OIDCLoginService,
+	auditLog *services.AuditLog,
 	tokenGen jwt.JWTService,
 	logger logger.Logger,
 	cache cache.CacheService,
 ) *UserGrpcServer {
 	return &UserGrpcServer{
-		userService: userService,
-		tokenGen:    tokenGen,
-		logger:      logger,
-		cache:       cache,
+		userService:      userService,
+		apiKeyService:    apiKeyService,
+		oidcLoginService: oidcLoginService,
+		auditLog:         auditLog,
+		tokenGen:         tokenGen,
+		logger:           logger,
+		cache:            cache,
 	}
 }
 
@@ -62,22 +77,104 @@ func (s *UserGrpcServer) LoginUser(ctx context.Context, req *userpb.LoginRequest
 		return nil, err
 	}
 
-	token, err := s.tokenGen.GenerateJWT(user.ID)
+	return s.loginResponse(ctx, user)
+}
+
+func (s *UserGrpcServer) StartOIDCLogin(ctx context.Context, req *userpb.StartOIDCLoginRequest) (*userpb.StartOIDCLoginResponse, error) {
+	authURL, state, err := s.oidcLoginService.StartLogin(ctx, req.GetProvider())
+	if err != nil {
+		s.logger.Errorf("Failed to start OIDC login with %s: %v", req.GetProvider(), err)
+		return nil, err
+	}
+
+	return &userpb.StartOIDCLoginResponse{
+		AuthorizationUrl: authURL,
+		State:            state,
+	}, nil
+}
+
+func (s *UserGrpcServer) CompleteOIDCLogin(ctx context.Context, req *userpb.CompleteOIDCLoginRequest) (*userpb.LoginResponse, error) {
+	user, err := s.oidcLoginService.CompleteLogin(ctx, req.GetState(), req.GetCode())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loginResponse(ctx, user)
+}
+
+// loginResponse finishes a first-factor login: users with two-factor
+// authentication get a challenge, everyone else a token pair.
+func (s *UserGrpcServer) loginResponse(ctx context.Context, user models.User) (*userpb.LoginResponse, error) {
+	if user.TwoFactor.Enabled {
+		challenge, err := s.userService.CreateTwoFactorChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+
+		return &userpb.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			Username:          user.Username,
+			Email:             user.Email,
+		}, nil
+	}
+
+	tokens, err := s.userService.IssueTokens(ctx, user)
 	if err != nil {
 		return nil, err
 	}
 
 	return &userpb.LoginResponse{
-		Token:    token,
-		Username: user.Username,
-		Email:    user.Email,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Format(time.RFC3339),
+		Username:     user.Username,
+		Email:        user.Email,
 	}, nil
 }
 
-func (s *UserGrpcServer) RetrieveProfile(ctx context.Context, req *userpb.RetrieveProfileRequest) (*userpb.RetrieveProfileResponse, error) {
-	userID := req.GetUserId()
+func (s *UserGrpcServer) VerifySecondFactor(ctx context.Context, req *userpb.VerifySecondFactorRequest) (*userpb.VerifySecondFactorResponse, error) {
+	user, err := s.userService.VerifySecondFactor(ctx, req.GetChallengeToken(), req.GetCode())
+	if err != nil {
+		s.logger.Errorf("Failed to verify second factor: %v", err)
+		return nil, err
+	}
+
+	tokens, err := s.userService.IssueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userpb.VerifySecondFactorResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Format(time.RFC3339),
+		Username:     user.Username,
+		Email:        user.Email,
+	}, nil
+}
 
-	user, err := s.userService.GetUserByID(ctx, userID)
+func (s *UserGrpcServer) RefreshToken(ctx context.Context, req *userpb.RefreshTokenRequest) (*userpb.RefreshTokenResponse, error) {
+	tokens, err := s.userService.RefreshToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		if err == errors.ErrTokenRevocationCheck {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return &userpb.RefreshTokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+func (s *UserGrpcServer) RetrieveProfile(ctx context.Context, req *userpb.RetrieveProfileRequest) (*userpb.RetrieveProfileResponse, error) {
+	user, err := s.userService.GetProfile(ctx, req.GetUserId())
 	if err != nil {
 		log.Printf("Error retrieving user: %v", err)
 		return nil, err
@@ -91,6 +188,45 @@ func (s *UserGrpcServer) RetrieveProfile(ctx context.Context, req *userpb.Retrie
 	return resp, nil
 }
 
+func (s *UserGrpcServer) UpdateProfile(ctx context.Context, req *userpb.UpdateProfileRequest) (*userpb.UpdateProfileResponse, error) {
+	user, err := s.userService.UpdateProfile(ctx, req.GetUserId(), req.GetUsername(), req.GetEmail())
+	if err != nil {
+		s.logger.Errorf("Failed to update profile: %v", err)
+		return nil, err
+	}
+
+	message := "Profile updated successfully"
+	if !user.IsEmailVerified() {
+		message = "Profile updated successfully, please verify your email address"
+	}
+
+	return &userpb.UpdateProfileResponse{
+		Username: user.Username,
+		Email:    user.Email,
+		Message:  message,
+	}, nil
+}
+
+func (s *UserGrpcServer) ChangePassword(ctx context.Context, req *userpb.ChangePasswordRequest) (*userpb.ChangePasswordResponse, error) {
+	user, err := s.userService.ChangePassword(ctx, req.GetCurrentPassword(), req.GetNewPassword())
+	if err != nil {
+		s.logger.Errorf("Failed to change password: %v", err)
+		return nil, err
+	}
+
+	tokens, err := s.userService.IssueTokens(ctx, *user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userpb.ChangePasswordResponse{
+		Message:      "Password changed successfully, other sessions have been logged out",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
 func (s *UserGrpcServer) DeleteUser(ctx context.Context, req *userpb.DeleteUserRequest) (*userpb.DeleteUserResponse, error) {
 
 	tokenString, err := s.tokenGen.ExtractTokenFromContext(ctx)
@@ -99,16 +235,377 @@ func (s *UserGrpcServer) DeleteUser(ctx context.Context, req *userpb.DeleteUserR
 		return nil, err
 	}
 
-	userID := req.GetUserId()
-
-	err = s.userService.DeleteUserAndOrders(ctx, userID, tokenString)
+	err = s.userService.DeleteAccount(ctx, req.GetUserId(), tokenString)
 
 	if err != nil {
-		s.logger.Errorf("Failed to delete user and orders: %v", err)
+		s.logger.Errorf("Failed to delete user: %v", err)
 		return nil, err
 	}
 
 	return &userpb.DeleteUserResponse{
-		Message: "User and associated orders deleted successfully",
+		Message: "Account deleted, it can be restored with the link sent by email",
+	}, nil
+}
+
+func (s *UserGrpcServer) RestoreAccount(ctx context.Context, req *userpb.RestoreAccountRequest) (*userpb.RestoreAccountResponse, error) {
+	if err := s.userService.RestoreAccount(ctx, req.GetToken()); err != nil {
+		s.logger.Errorf("Failed to restore account: %v", err)
+		return nil, err
+	}
+
+	return &userpb.RestoreAccountResponse{
+		Message: "Account restored successfully",
+	}, nil
+}
+
+func (s *UserGrpcServer) Logout(ctx context.Context, req *userpb.LogoutRequest) (*userpb.LogoutResponse, error) {
+	tokenString, err := s.tokenGen.ExtractTokenFromContext(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to extract token from context: %v", err)
+		return nil, err
+	}
+
+	if err := s.userService.Logout(ctx, tokenString); err != nil {
+		s.logger.Errorf("Failed to log out: %v", err)
+		return nil, err
+	}
+
+	return &userpb.LogoutResponse{
+		Message: "Logged out successfully",
+	}, nil
+}
+
+func (s *UserGrpcServer) LogoutAllSessions(ctx context.Context, req *userpb.LogoutAllSessionsRequest) (*userpb.LogoutAllSessionsResponse, error) {
+	if err := s.userService.LogoutAllSessions(ctx, req.GetUserId()); err != nil {
+		s.logger.Errorf("Failed to revoke all sessions: %v", err)
+		return nil, err
+	}
+
+	return &userpb.LogoutAllSessionsResponse{
+		Message: "All sessions have been logged out",
+	}, nil
+}
+
+func (s *UserGrpcServer) ListSessions(ctx context.Context, req *userpb.ListSessionsRequest) (*userpb.ListSessionsResponse, error) {
+	sessions, err := s.userService.ListSessions(ctx, req.GetUserId())
+	if err != nil {
+		s.logger.Errorf("Failed to list sessions: %v", err)
+		return nil, err
+	}
+
+	var currentSessionID string
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		currentSessionID = principal.SessionID
+	}
+
+	resp := &userpb.ListSessionsResponse{}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &userpb.Session{
+			Id:         session.ID,
+			Jti:        session.TokenID,
+			UserAgent:  session.UserAgent,
+			Ip:         session.IP,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastSeenAt: session.LastSeenAt.Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+func (s *UserGrpcServer) RevokeSession(ctx context.Context, req *userpb.RevokeSessionRequest) (*userpb.RevokeSessionResponse, error) {
+	if err := s.userService.RevokeSession(ctx, req.GetUserId(), req.GetSessionId()); err != nil {
+		s.logger.Errorf("Failed to revoke session: %v", err)
+		return nil, err
+	}
+
+	return &userpb.RevokeSessionResponse{
+		Message: "Session revoked",
+	}, nil
+}
+
+// ExportUserData streams the user's data export in chunks, as JSON or as a
+// zip archive depending on the requested format.
+func (s *UserGrpcServer) ExportUserData(req *userpb.ExportUserDataRequest, stream userpb.UserService_ExportUserDataServer) error {
+	w := bufio.NewWriterSize(&exportChunkWriter{stream: stream}, exportChunkSize)
+
+	if err := s.userService.ExportUserData(stream.Context(), req.GetUserId(), req.GetFormat(), w); err != nil {
+		s.logger.Errorf("Failed to export user data: %v", err)
+		return err
+	}
+
+	return w.Flush()
+}
+
+func (s *UserGrpcServer) RequestPasswordReset(ctx context.Context, req *userpb.RequestPasswordResetRequest) (*userpb.RequestPasswordResetResponse, error) {
+	if err := s.userService.RequestPasswordReset(ctx, req.GetEmail()); err != nil {
+		s.logger.Errorf("Failed to request password reset: %v", err)
+		return nil, err
+	}
+
+	return &userpb.RequestPasswordResetResponse{
+		Message: "If an account with this email exists, a password reset link has been sent",
+	}, nil
+}
+
+func (s *UserGrpcServer) ConfirmPasswordReset(ctx context.Context, req *userpb.ConfirmPasswordResetRequest) (*userpb.ConfirmPasswordResetResponse, error) {
+	if err := s.userService.ConfirmPasswordReset(ctx, req.GetToken(), req.GetNewPassword()); err != nil {
+		s.logger.Errorf("Failed to reset password: %v", err)
+		return nil, err
+	}
+
+	return &userpb.ConfirmPasswordResetResponse{
+		Message: "Password has been reset successfully",
+	}, nil
+}
+
+func (s *UserGrpcServer) VerifyEmail(ctx context.Context, req *userpb.VerifyEmailRequest) (*userpb.VerifyEmailResponse, error) {
+	if err := s.userService.VerifyEmail(ctx, req.GetToken()); err != nil {
+		s.logger.Errorf("Failed to verify email: %v", err)
+		return nil, err
+	}
+
+	return &userpb.VerifyEmailResponse{
+		Message: "Email address verified successfully",
+	}, nil
+}
+
+func (s *UserGrpcServer) ResendVerificationEmail(ctx context.Context, req *userpb.ResendVerificationEmailRequest) (*userpb.ResendVerificationEmailResponse, error) {
+	if err := s.userService.ResendVerificationEmail(ctx, req.GetEmail()); err != nil {
+		s.logger.Errorf("Failed to resend verification email: %v", err)
+		return nil, err
+	}
+
+	return &userpb.ResendVerificationEmailResponse{
+		Message: "If the address is registered and not yet verified, a new verification email has been sent",
+	}, nil
+}
+
+func (s *UserGrpcServer) UnlockAccount(ctx context.Context, req *userpb.UnlockAccountRequest) (*userpb.UnlockAccountResponse, error) {
+	if err := s.userService.UnlockAccount(ctx, req.GetToken()); err != nil {
+		s.logger.Errorf("Failed to unlock account: %v", err)
+		return nil, err
+	}
+
+	return &userpb.UnlockAccountResponse{
+		Message: "Account unlocked, you can log in again",
+	}, nil
+}
+
+func (s *UserGrpcServer) EnrollTwoFactor(ctx context.Context, req *userpb.EnrollTwoFactorRequest) (*userpb.EnrollTwoFactorResponse, error) {
+	secret, uri, err := s.userService.EnrollTwoFactor(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to start two-factor enrollment: %v", err)
+		return nil, err
+	}
+
+	return &userpb.EnrollTwoFactorResponse{
+		Secret:          secret,
+		ProvisioningUri: uri,
+	}, nil
+}
+
+func (s *UserGrpcServer) ConfirmTwoFactor(ctx context.Context, req *userpb.ConfirmTwoFactorRequest) (*userpb.ConfirmTwoFactorResponse, error) {
+	recoveryCodes, err := s.userService.ConfirmTwoFactor(ctx, req.GetCode())
+	if err != nil {
+		s.logger.Errorf("Failed to confirm two-factor enrollment: %v", err)
+		return nil, err
+	}
+
+	return &userpb.ConfirmTwoFactorResponse{
+		RecoveryCodes: recoveryCodes,
+		Message:       "Two-factor authentication enabled, store the recovery codes in a safe place",
+	}, nil
+}
+
+func (s *UserGrpcServer) DisableTwoFactor(ctx context.Context, req *userpb.DisableTwoFactorRequest) (*userpb.DisableTwoFactorResponse, error) {
+	if err := s.userService.DisableTwoFactor(ctx, req.GetCode()); err != nil {
+		s.logger.Errorf("Failed to disable two-factor authentication: %v", err)
+		return nil, err
+	}
+
+	return &userpb.DisableTwoFactorResponse{
+		Message: "Two-factor authentication disabled",
+	}, nil
+}
+
+func (s *UserGrpcServer) CreateAPIKey(ctx context.Context, req *userpb.CreateAPIKeyRequest) (*userpb.CreateAPIKeyResponse, error) {
+	ttl := time.Duration(req.GetTtlSeconds()) * time.Second
+
+	rawKey, key, err := s.apiKeyService.CreateAPIKey(ctx, req.GetName(), req.GetScopes(), ttl)
+	if err != nil {
+		s.logger.Errorf("Failed to create API key: %v", err)
+		return nil, err
+	}
+
+	return &userpb.CreateAPIKeyResponse{
+		Key:    rawKey,
+		ApiKey: toAPIKeyProto(key),
+	}, nil
+}
+
+func (s *UserGrpcServer) ListAPIKeys(ctx context.Context, req *userpb.ListAPIKeysRequest) (*userpb.ListAPIKeysResponse, error) {
+	keys, err := s.apiKeyService.ListAPIKeys(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to list API keys: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to list API keys")
+	}
+
+	resp := &userpb.ListAPIKeysResponse{}
+	for _, key := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, toAPIKeyProto(key))
+	}
+	return resp, nil
+}
+
+func (s *UserGrpcServer) RevokeAPIKey(ctx context.Context, req *userpb.RevokeAPIKeyRequest) (*userpb.RevokeAPIKeyResponse, error) {
+	if err := s.apiKeyService.RevokeAPIKey(ctx, req.GetId()); err != nil {
+		s.logger.Errorf("Failed to revoke API key: %v", err)
+		return nil, err
+	}
+
+	return &userpb.RevokeAPIKeyResponse{
+		Message: "API key revoked",
+	}, nil
+}
+
+func (s *UserGrpcServer) SearchUsers(ctx context.Context, req *userpb.SearchUsersRequest) (*userpb.SearchUsersResponse, error) {
+	filter := repositories.UserFilter{
+		Email:    req.GetEmail(),
+		Username: req.GetUsername(),
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseOptionalTime(req.GetCreatedAfter()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid created_after: %v", err)
+	}
+	if filter.CreatedBefore, err = parseOptionalTime(req.GetCreatedBefore()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid created_before: %v", err)
+	}
+
+	users, total, err := s.userService.SearchUsers(ctx, filter, int(req.GetPage()), int(req.GetPageSize()))
+	if err != nil {
+		s.logger.Errorf("Failed to search users: %v", err)
+		return nil, err
+	}
+
+	resp := &userpb.SearchUsersResponse{
+		Total:    total,
+		Page:     req.GetPage(),
+		PageSize: req.GetPageSize(),
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, &userpb.AdminUser{
+			Id:                    user.ID,
+			Username:              user.Username,
+			Email:                 user.Email,
+			Roles:                 user.Roles,
+			Status:                user.Status,
+			Disabled:              user.Disabled,
+			PasswordResetRequired: user.PasswordResetRequired,
+			TwoFactorEnabled:      user.TwoFactor.Enabled,
+			CreatedAt:             user.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:             user.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	return resp, nil
+}
+
+func (s *UserGrpcServer) DisableUser(ctx context.Context, req *userpb.DisableUserRequest) (*userpb.DisableUserResponse, error) {
+	if err := s.userService.SetUserDisabled(ctx, req.GetUserId(), true); err != nil {
+		s.logger.Errorf("Failed to disable user: %v", err)
+		return nil, err
+	}
+
+	return &userpb.DisableUserResponse{
+		Message: "User disabled",
+	}, nil
+}
+
+func (s *UserGrpcServer) EnableUser(ctx context.Context, req *userpb.EnableUserRequest) (*userpb.EnableUserResponse, error) {
+	if err := s.userService.SetUserDisabled(ctx, req.GetUserId(), false); err != nil {
+		s.logger.Errorf("Failed to enable user: %v", err)
+		return nil, err
+	}
+
+	return &userpb.EnableUserResponse{
+		Message: "User enabled",
 	}, nil
 }
+
+func (s *UserGrpcServer) ForcePasswordReset(ctx context.Context, req *userpb.ForcePasswordResetRequest) (*userpb.ForcePasswordResetResponse, error) {
+	if err := s.userService.ForcePasswordReset(ctx, req.GetUserId()); err != nil {
+		s.logger.Errorf("Failed to force password reset: %v", err)
+		return nil, err
+	}
+
+	return &userpb.ForcePasswordResetResponse{
+		Message: "Password reset required, a reset link was sent to the user",
+	}, nil
+}
+
+func (s *UserGrpcServer) SetUserRoles(ctx context.Context, req *userpb.SetUserRolesRequest) (*userpb.SetUserRolesResponse, error) {
+	if err := s.userService.SetUserRoles(ctx, req.GetUserId(), req.GetRoles()); err != nil {
+		s.logger.Errorf("Failed to set user roles: %v", err)
+		return nil, err
+	}
+
+	return &userpb.SetUserRolesResponse{
+		Message: "Roles updated",
+	}, nil
+}
+
+func (s *UserGrpcServer) ListAuditEvents(ctx context.Context, req *userpb.ListAuditEventsRequest) (*userpb.ListAuditEventsResponse, error) {
+	events, total, err := s.auditLog.ListAuditEvents(ctx, req.GetUserId(), req.GetAction(), int(req.GetPage()), int(req.GetPageSize()))
+	if err != nil {
+		s.logger.Errorf("Failed to list audit events: %v", err)
+		return nil, err
+	}
+
+	resp := &userpb.ListAuditEventsResponse{Total: total}
+	for _, event := range events {
+		resp.Events = append(resp.Events, &userpb.AuditEvent{
+			Id:         event.ID,
+			OccurredAt: event.OccurredAt.Format(time.RFC3339),
+			ActorType:  event.ActorType,
+			ActorId:    event.ActorID,
+			Action:     event.Action,
+			TargetId:   event.TargetID,
+			Ip:         event.IP,
+			UserAgent:  event.UserAgent,
+			Outcome:    event.Outcome,
+			Reason:     event.Reason,
+			Details:    event.Details,
+		})
+	}
+	return resp, nil
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func toAPIKeyProto(key models.APIKey) *userpb.APIKey {
+	resp := &userpb.APIKey{
+		Id:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedBy: key.CreatedBy,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.ExpiresAt != nil {
+		resp.ExpiresAt = key.ExpiresAt.Format(time.RFC3339)
+	}
+	if key.LastUsedAt != nil {
+		resp.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+	}
+	if key.RevokedAt != nil {
+		resp.RevokedAt = key.RevokedAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	"user-service/internal/core/models"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	EnsureIndexes(ctx context.Context) error
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+	"user-service/internal/core/models"
+)
+
+// AuditRepository stores audit events. Events are never deleted, and only
+// updated to remove personal data once an account is purged.
+type AuditRepository interface {
+	InsertEvents(ctx context.Context, events []models.AuditEvent) error
+	// ListEvents returns the events concerning a user, newest first, and their
+	// total count. An empty action matches every action; a zero limit returns
+	// all events.
+	ListEvents(ctx context.Context, targetID, action string, skip, limit int64) ([]models.AuditEvent, int64, error)
+	// AnonymizeEvents removes the client address, user agent and email from
+	// the events concerning or made by a user and returns how many it changed.
+	AnonymizeEvents(ctx context.Context, userID string) (int64, error)
+	EnsureIndexes(ctx context.Context) error
+}
@@ -2,13 +2,24 @@ package repositories
 
 import (
 	"context"
+	"time"
 	"user-service/internal/core/models"
 )
 
 type OrderRepository interface {
 	CreateOrder(ctx context.Context, order *models.Order) error
 	GetOrderByID(ctx context.Context, id string) (*models.Order, error)
-	UpdateOrder(ctx context.Context, id string, status string) error
-	GetOrdersByUserID (ctx context.Context, userID string) ([]*models.Order, error)
-	DeleteOrdersByUserID (ctx context.Context, userID string) error
+	// TransitionOrderStatus applies change only if the order is still in status
+	// from and reports whether it did.
+	TransitionOrderStatus(ctx context.Context, id string, from string, change models.OrderStatusChange) (bool, error)
+	// GetExpiredReservations returns up to limit pending orders whose stock
+	// reservation expired before the given time.
+	GetExpiredReservations(ctx context.Context, before time.Time, limit int64) ([]*models.Order, error)
+	GetOrdersByUserID(ctx context.Context, userID string) ([]*models.Order, error)
+	// MigrateMoney converts the float prices stored by older versions to
+	// models.Money in currency and returns how many orders it changed.
+	MigrateMoney(ctx context.Context, currency string) (int64, error)
+	// MigrateStatuses renames the completed status of older versions to
+	// delivered and returns how many orders it changed.
+	MigrateStatuses(ctx context.Context) (int64, error)
 }
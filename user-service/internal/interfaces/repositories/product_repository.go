@@ -7,9 +7,17 @@ import (
 
 type ProductRepository interface {
 	CreateProduct(ctx context.Context, product models.Product) (models.Product, error)
+	// GetProductByID returns errors.ErrProductNotFound for an unknown id.
 	GetProductByID(ctx context.Context, id string) (models.Product, error)
 	UpdateProduct(ctx context.Context, id string, product models.Product) (models.Product, error)
 	DeleteProduct(ctx context.Context, id string) error
 	ListProducts(ctx context.Context, filter map[string]interface{}, skip, limit int64) ([]models.Product, error)
-	DecreaseStock(ctx context.Context, productID string, quantity int) (models.Product, error)
+	// MigrateMoney converts the float prices stored by older versions to
+	// models.Money in currency and returns how many products it changed.
+	MigrateMoney(ctx context.Context, currency string) (int64, error)
+	// ReserveStock takes quantity units out of stock if at least that many are
+	// available and reports whether it did.
+	ReserveStock(ctx context.Context, productID string, quantity int) (bool, error)
+	// ReleaseStock puts quantity units back into stock.
+	ReleaseStock(ctx context.Context, productID string, quantity int) error
 }
@@ -2,15 +2,41 @@ package repositories
 
 import (
 	"context"
+	"time"
 	"user-service/internal/core/models"
 )
 
+// UserFilter narrows a user search. Email and Username match by
+// case-insensitive prefix; zero values are ignored.
+type UserFilter struct {
+	Email         string
+	Username      string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user models.User) (models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (models.User, error)
 	AuthenticateUser(ctx context.Context, email, password string) (models.User, error)
 	GetUserByID(ctx context.Context, userID string) (models.User, error)
-	DeleteUser(ctx context.Context, userID string) error
+	GetUserByExternalIdentity(ctx context.Context, provider, subject string) (models.User, error)
+	LinkExternalIdentity(ctx context.Context, userID string, identity models.ExternalIdentity) error
+	SoftDeleteUser(ctx context.Context, userID string, deletedAt time.Time) error
+	RestoreUser(ctx context.Context, userID string) (bool, error)
+	GetUsersDeletedBefore(ctx context.Context, cutoff time.Time, limit int64) ([]models.User, error)
+	AnonymizeUser(ctx context.Context, userID string, anonymizedAt time.Time) error
+	SearchUsers(ctx context.Context, filter UserFilter, skip, limit int64) ([]models.User, int64, error)
+	SetDisabled(ctx context.Context, userID string, disabled bool) error
+	SetPasswordResetRequired(ctx context.Context, userID string) error
+	UpdateRoles(ctx context.Context, userID string, roles []string) error
+	EnsureIndexes(ctx context.Context) error
+	UpdateProfile(ctx context.Context, user models.User) error
+	UpdatePassword(ctx context.Context, userID string, hashedPassword string) error
+	// MarkEmailVerified verifies the account only while its address is still
+	// email and reports whether it did.
+	MarkEmailVerified(ctx context.Context, userID, email string) (bool, error)
+	UpdateTwoFactor(ctx context.Context, userID string, twoFactor models.TwoFactor) error
+	ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error)
 }
-
@@ -1,5 +1,11 @@
 package services
 
+import "time"
+
 type EmailService interface {
 	SendWelcomeEmail(to string) error
+	SendPasswordResetEmail(to, token string, expiresIn time.Duration) error
+	SendVerificationEmail(to, token string, expiresIn time.Duration) error
+	SendAccountLockedEmail(to, unlockToken string, lockedFor time.Duration) error
+	SendAccountDeletedEmail(to, restoreToken string, restorableFor time.Duration) error
 }
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/security"
+)
+
+const (
+	accountRestorePrefix = "account_restore:"
+	purgeBatchSize       = 100
+)
+
+var errAccountDeleted = status.Error(codes.FailedPrecondition, "this account has been deleted, use the link from the deletion email to restore it")
+
+// DeleteAccount soft-deletes an account. The user is logged out everywhere
+// and can restore the account with the emailed token until the grace period
+// ends. Orders are never deleted.
+func (u *UserService) DeleteAccount(ctx context.Context, userID string, tokenString string) error {
+	userID, err := authorizeUser(ctx, strings.TrimSpace(userID))
+	if err != nil {
+		return err
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user.ID == "" || user.IsDeleted() {
+		return status.Errorf(codes.NotFound, "user with id %s not found", userID)
+	}
+
+	if err := u.userRepo.SoftDeleteUser(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+
+	if err := u.jwtService.InvalidateToken(tokenString); err != nil {
+		return err
+	}
+
+	if err := u.revokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", userID)); err != nil {
+		u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", userID, err)
+	}
+
+	if err := u.sendRestoreEmail(user.ID, user.Email); err != nil {
+		u.logger.Errorf("Failed to send account deletion email to user %s: %v", userID, err)
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditAccountDeleted, TargetID: userID})
+	u.logger.Infof("User %s deleted, restorable for %s", userID, u.deletionGracePeriod)
+	return nil
+}
+
+func (u *UserService) sendRestoreEmail(userID, email string) error {
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := u.cache.Set(accountRestorePrefix+security.HashToken(token), userID, u.deletionGracePeriod); err != nil {
+		return err
+	}
+
+	return u.email.SendAccountDeletedEmail(email, token, u.deletionGracePeriod)
+}
+
+// RestoreAccount undoes a deletion using the token from the deletion email.
+func (u *UserService) RestoreAccount(ctx context.Context, token string) error {
+	key := accountRestorePrefix + security.HashToken(token)
+
+	userID, err := u.cache.Get(key)
+	if err != nil || userID == "" {
+		return status.Error(codes.InvalidArgument, "invalid or expired restore token")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user.ID == "" {
+		return status.Error(codes.InvalidArgument, "invalid or expired restore token")
+	}
+
+	if !user.IsDeleted() || user.AnonymizedAt != nil || time.Since(*user.DeletedAt) > u.deletionGracePeriod {
+		return status.Error(codes.FailedPrecondition, "the account can no longer be restored")
+	}
+
+	// of several concurrent restores only one consumes the token
+	if consumed, err := u.cache.GetAndDelete(key); err != nil || consumed != userID {
+		return status.Error(codes.InvalidArgument, "invalid or expired restore token")
+	}
+
+	restored, err := u.userRepo.RestoreUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return status.Error(codes.FailedPrecondition, "the account can no longer be restored")
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditAccountRestored, TargetID: userID})
+	u.logger.Infof("User %s restored", userID)
+	return nil
+}
+
+// PurgeDeletedAccounts anonymizes the accounts whose grace period has ended,
+// along with their audit events, and returns how many were processed. Running
+// it on several replicas at once is safe: an account is only anonymized once.
+func (u *UserService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-u.deletionGracePeriod)
+	purged := 0
+
+	for {
+		users, err := u.userRepo.GetUsersDeletedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, user := range users {
+			// before the account, so that a failure is retried on the next run
+			if err := u.audit.anonymize(ctx, user.ID); err != nil {
+				return purged, err
+			}
+			if err := u.userRepo.AnonymizeUser(ctx, user.ID, time.Now()); err != nil {
+				return purged, err
+			}
+
+			if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", user.ID)); err != nil {
+				u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", user.ID, err)
+			}
+			u.audit.Record(ctx, models.AuditEvent{ActorType: models.AuditActorSystem, Action: models.AuditAccountAnonymized, TargetID: user.ID})
+			purged++
+		}
+
+		if len(users) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// StartAccountPurger blocks and purges expired deleted accounts every interval.
+func (u *UserService) StartAccountPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := u.PurgeDeletedAccounts(ctx)
+			if err != nil {
+				u.logger.Errorf("Failed to purge deleted accounts: %v", err)
+			}
+			if purged > 0 {
+				u.logger.Infof("Anonymized %d deleted accounts", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/jwt"
+	"user-service/internal/infrastructure/utils/security"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	t.Run("Deletes and logs the user out", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		tokens, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+		other, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+
+		require.NoError(t, f.service.DeleteAccount(userContext("user-1"), "user-1", tokens.AccessToken))
+
+		assert.True(t, f.users.get("user-1").IsDeleted())
+		_, err = f.jwt.VerifyToken(tokens.AccessToken)
+		assert.Error(t, err)
+		_, err = f.jwt.VerifyToken(other.AccessToken)
+		assert.Error(t, err)
+		_, err = f.service.RefreshToken(context.Background(), other.RefreshToken)
+		assert.Error(t, err)
+
+		_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t, "alice@example.com", f.email.last("account_deleted").to)
+	})
+
+	t.Run("Users cannot delete other accounts", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		err := f.service.DeleteAccount(userContext("user-2"), "user-1", "")
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.False(t, f.users.get("user-1").IsDeleted())
+	})
+
+	t.Run("An account is deleted once", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		tokens, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+		require.NoError(t, f.service.DeleteAccount(userContext("user-1"), "user-1", tokens.AccessToken))
+
+		err = f.service.DeleteAccount(userContext("user-1"), "user-1", tokens.AccessToken)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, 1, f.email.count("account_deleted"))
+	})
+}
+
+func TestRestoreAccount(t *testing.T) {
+	deleteAccount := func(t *testing.T, f *userServiceFixture) string {
+		t.Helper()
+
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		tokens, err := f.jwt.GenerateTokenPair(jwt.NewIdentity(user))
+		require.NoError(t, err)
+		require.NoError(t, f.service.DeleteAccount(userContext("user-1"), "user-1", tokens.AccessToken))
+		return f.email.last("account_deleted").token
+	}
+
+	t.Run("Restores with the emailed token", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		token := deleteAccount(t, f)
+
+		require.NoError(t, f.service.RestoreAccount(context.Background(), token))
+
+		assert.False(t, f.users.get("user-1").IsDeleted())
+		_, err := f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.NoError(t, err)
+
+		err = f.service.RestoreAccount(context.Background(), token)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "the token is single use")
+	})
+
+	t.Run("Concurrent restores consume the token once", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		token := deleteAccount(t, f)
+
+		var restored atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if f.service.RestoreAccount(context.Background(), token) == nil {
+					restored.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), restored.Load())
+		exists, err := f.cache.Exists(accountRestorePrefix + security.HashToken(token))
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("Rejects unknown tokens", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		deleteAccount(t, f)
+
+		err := f.service.RestoreAccount(context.Background(), "not-a-token")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.True(t, f.users.get("user-1").IsDeleted())
+	})
+
+	t.Run("Not after the grace period", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		token := deleteAccount(t, f)
+		require.NoError(t, f.users.SoftDeleteUser(context.Background(), "user-1", time.Now().Add(-31*24*time.Hour)))
+
+		err := f.service.RestoreAccount(context.Background(), token)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.True(t, f.users.get("user-1").IsDeleted())
+	})
+
+	t.Run("Not once anonymized", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		token := deleteAccount(t, f)
+		require.NoError(t, f.users.AnonymizeUser(context.Background(), "user-1", time.Now()))
+
+		err := f.service.RestoreAccount(context.Background(), token)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestPurgeDeletedAccounts(t *testing.T) {
+	t.Run("Anonymizes accounts past the grace period", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		expired := time.Now().Add(-31 * 24 * time.Hour)
+		for i := 0; i < purgeBatchSize+5; i++ {
+			id := fmt.Sprintf("expired-%d", i)
+			f.addUser(t, id, id+"@example.com", "Password123")
+			require.NoError(t, f.users.SoftDeleteUser(context.Background(), id, expired))
+		}
+		f.addUser(t, "recent", "recent@example.com", "Password123")
+		require.NoError(t, f.users.SoftDeleteUser(context.Background(), "recent", time.Now().Add(-24*time.Hour)))
+		f.addUser(t, "active", "active@example.com", "Password123")
+
+		purged, err := f.service.PurgeDeletedAccounts(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, purgeBatchSize+5, purged)
+
+		user := f.users.get("expired-0")
+		assert.NotNil(t, user.AnonymizedAt)
+		assert.NotContains(t, user.Email, "expired-0@example.com")
+		assert.Empty(t, user.Password)
+		assert.Nil(t, f.users.get("recent").AnonymizedAt)
+		assert.Equal(t, "active@example.com", f.users.get("active").Email)
+
+		purged, err = f.service.PurgeDeletedAccounts(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, purged, "accounts are anonymized once")
+	})
+
+	t.Run("Scrubs personal data from the audit log", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		f.addUser(t, "user-2", "bob@example.com", "Password123")
+
+		for _, userID := range []string{"user-1", "user-2"} {
+			require.NoError(t, f.audit.InsertEvents(context.Background(), []models.AuditEvent{{
+				Action:    models.AuditLogin,
+				ActorType: models.AuditActorUser,
+				ActorID:   userID,
+				TargetID:  userID,
+				IP:        "203.0.113.7",
+				UserAgent: "curl/8.0",
+				Details:   map[string]string{"method": "password", "email": userID + "@example.com"},
+			}}))
+		}
+
+		require.NoError(t, f.users.SoftDeleteUser(context.Background(), "user-1", time.Now().Add(-31*24*time.Hour)))
+
+		purged, err := f.service.PurgeDeletedAccounts(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+
+		for _, event := range f.audit.all() {
+			if event.TargetID == "user-1" {
+				assert.Empty(t, event.IP)
+				assert.Empty(t, event.UserAgent)
+				assert.NotContains(t, event.Details, "email")
+				assert.Equal(t, "password", event.Details["method"])
+			} else {
+				assert.Equal(t, "203.0.113.7", event.IP)
+				assert.Equal(t, "user-2@example.com", event.Details["email"])
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	"user-service/internal/errors"
+	"user-service/internal/infrastructure/utils/security"
+	"user-service/internal/infrastructure/utils/uuid"
+	logger "user-service/internal/interfaces/logger"
+	"user-service/internal/interfaces/repositories"
+)
+
+const (
+	apiKeyPrefix       = "sk_"
+	apiKeyDisplayChars = 8
+	// last_used_at is only written once per interval to avoid a database write
+	// on every request.
+	apiKeyTouchInterval = time.Minute
+)
+
+// ScopeAuthorizer checks that principal may grant an API key access to the
+// gRPC method.
+type ScopeAuthorizer func(principal *auth.Principal, method string) error
+
+type APIKeyService struct {
+	apiKeyRepo     repositories.APIKeyRepository
+	uuidGenerator  uuid.Generator
+	logger         logger.Logger
+	authorizeScope ScopeAuthorizer
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepository, uuidGenerator uuid.Generator, logger logger.Logger, authorizeScope ScopeAuthorizer) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:     apiKeyRepo,
+		uuidGenerator:  uuidGenerator,
+		logger:         logger,
+		authorizeScope: authorizeScope,
+	}
+}
+
+// CreateAPIKey returns the plaintext key, which is not stored and cannot be
+// shown again. A zero ttl creates a key that does not expire. Keys are managed
+// by admins only, so a key can never mint or revoke other keys.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, name string, scopes []string, ttl time.Duration) (string, models.APIKey, error) {
+	principal, err := authorizeAdmin(ctx)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", models.APIKey{}, status.Error(codes.InvalidArgument, "API key name is required")
+	}
+	if len(scopes) == 0 {
+		return "", models.APIKey{}, status.Error(codes.InvalidArgument, "at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !strings.HasPrefix(scope, "/") {
+			return "", models.APIKey{}, status.Errorf(codes.InvalidArgument, "scope %q must be a full gRPC method name", scope)
+		}
+		if err := s.authorizeScope(principal, scope); err != nil {
+			return "", models.APIKey{}, err
+		}
+	}
+	if ttl < 0 {
+		return "", models.APIKey{}, status.Error(codes.InvalidArgument, "ttl must not be negative")
+	}
+
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	rawKey := apiKeyPrefix + token
+
+	key := models.APIKey{
+		ID:        s.uuidGenerator.GenerateUUID(),
+		Name:      name,
+		Prefix:    rawKey[:len(apiKeyPrefix)+apiKeyDisplayChars],
+		Hash:      security.HashToken(rawKey),
+		Scopes:    scopes,
+		CreatedBy: principal.UserID,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		expiresAt := key.CreatedAt.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return "", models.APIKey{}, err
+	}
+
+	s.logger.Infof("API key %s (%s) created by user %s", key.ID, key.Name, key.CreatedBy)
+	return rawKey, key, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.apiKeyRepo.ListAPIKeys(ctx)
+}
+
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	principal, err := authorizeAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.apiKeyRepo.RevokeAPIKey(ctx, id, time.Now()); err != nil {
+		if err == errors.ErrAPIKeyNotFound {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		return err
+	}
+
+	s.logger.Infof("API key %s revoked by user %s", id, principal.UserID)
+	return nil
+}
+
+// Authenticate resolves a raw key into a principal if the key is active and
+// scoped for method.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey, method string) (*auth.Principal, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, security.HashToken(rawKey))
+	if err != nil {
+		if err != errors.ErrAPIKeyNotFound {
+			s.logger.Errorf("Failed to look up API key: %v", err)
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return nil, status.Error(codes.Unauthenticated, "API key is expired or revoked")
+	}
+
+	principal := &auth.Principal{
+		Type:     auth.PrincipalAPIKey,
+		APIKeyID: key.ID,
+		Scopes:   key.Scopes,
+	}
+	if !principal.HasScope(method) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key is not allowed to call %s", method)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			s.logger.Errorf("Failed to update last use of API key %s: %v", key.ID, err)
+		}
+	}
+
+	return principal, nil
+}
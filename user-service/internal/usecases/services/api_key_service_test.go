@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	stdlogger "user-service/internal/infrastructure/logger"
+	"user-service/internal/infrastructure/utils/uuid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	searchUsersMethod = "/user.UserService/SearchUsers"
+	listOrdersMethod  = "/ecommerce/.order.OrderService/GetOrderByUserID"
+)
+
+// allowScopes lets the caller grant any scope listed, like
+// middleware.AuthorizeScope does for protected methods.
+func allowScopes(methods ...string) ScopeAuthorizer {
+	return func(principal *auth.Principal, method string) error {
+		for _, allowed := range methods {
+			if method == allowed {
+				return nil
+			}
+		}
+		return status.Errorf(codes.PermissionDenied, "you cannot grant access to %s", method)
+	}
+}
+
+func newTestAPIKeyService() (*APIKeyService, *fakeAPIKeyRepo) {
+	repo := newFakeAPIKeyRepo()
+	return NewAPIKeyService(repo, uuid.NewUUIDService(), &stdlogger.StdLogger{}, allowScopes(searchUsersMethod, listOrdersMethod)), repo
+}
+
+func apiKeyContext() context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Type: auth.PrincipalAPIKey, APIKeyID: "key-0", Scopes: []string{"/user.UserService/CreateAPIKey"}})
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("Records the creating admin", func(t *testing.T) {
+		svc, _ := newTestAPIKeyService()
+
+		rawKey, key, err := svc.CreateAPIKey(userContext("admin-1", models.RoleAdmin), "batch", []string{listOrdersMethod}, time.Hour)
+		require.NoError(t, err)
+		assert.NotEmpty(t, rawKey)
+		assert.Equal(t, "admin-1", key.CreatedBy)
+		require.NotNil(t, key.ExpiresAt)
+	})
+
+	t.Run("Requires an admin user", func(t *testing.T) {
+		svc, _ := newTestAPIKeyService()
+
+		_, _, err := svc.CreateAPIKey(userContext("user-1"), "batch", []string{listOrdersMethod}, 0)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, _, err = svc.CreateAPIKey(apiKeyContext(), "batch", []string{listOrdersMethod}, 0)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Rejects scopes the caller cannot grant", func(t *testing.T) {
+		svc, repo := newTestAPIKeyService()
+
+		_, _, err := svc.CreateAPIKey(userContext("admin-1", models.RoleAdmin), "batch", []string{listOrdersMethod, "/user.UserService/LoginUser"}, 0)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Empty(t, repo.keys)
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	svc, _ := newTestAPIKeyService()
+	admin := userContext("admin-1", models.RoleAdmin)
+
+	_, key, err := svc.CreateAPIKey(admin, "batch", []string{listOrdersMethod}, 0)
+	require.NoError(t, err)
+
+	err = svc.RevokeAPIKey(apiKeyContext(), key.ID)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	err = svc.RevokeAPIKey(userContext("user-1"), key.ID)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	assert.NoError(t, svc.RevokeAPIKey(admin, key.ID))
+	err = svc.RevokeAPIKey(admin, key.ID)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	t.Run("Active key in scope", func(t *testing.T) {
+		svc, repo := newTestAPIKeyService()
+		rawKey, key, err := svc.CreateAPIKey(userContext("admin-1", models.RoleAdmin), "batch", []string{listOrdersMethod}, time.Hour)
+		require.NoError(t, err)
+
+		principal, err := svc.Authenticate(context.Background(), rawKey, listOrdersMethod)
+		require.NoError(t, err)
+		assert.True(t, principal.IsAPIKey())
+		assert.Equal(t, key.ID, principal.APIKeyID)
+		assert.Equal(t, 1, repo.touched)
+
+		_, err = svc.Authenticate(context.Background(), rawKey, listOrdersMethod)
+		require.NoError(t, err)
+		assert.Equal(t, 1, repo.touched, "last use is only written once per interval")
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		svc, _ := newTestAPIKeyService()
+
+		_, err := svc.Authenticate(context.Background(), "sk_unknown", listOrdersMethod)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Expired key", func(t *testing.T) {
+		svc, repo := newTestAPIKeyService()
+		rawKey, key, err := svc.CreateAPIKey(userContext("admin-1", models.RoleAdmin), "batch", []string{listOrdersMethod}, time.Hour)
+		require.NoError(t, err)
+
+		expired := time.Now().Add(-time.Minute)
+		key.ExpiresAt = &expired
+		repo.keys[key.ID] = key
+
+		_, err = svc.Authenticate(context.Background(), rawKey, listOrdersMethod)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Revoked key", func(t *testing.T) {
+		svc, _ := newTestAPIKeyService()
+		admin := userContext("admin-1", models.RoleAdmin)
+		rawKey, key, err := svc.CreateAPIKey(admin, "batch", []string{listOrdersMethod}, 0)
+		require.NoError(t, err)
+		require.NoError(t, svc.RevokeAPIKey(admin, key.ID))
+
+		_, err = svc.Authenticate(context.Background(), rawKey, listOrdersMethod)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Method out of scope", func(t *testing.T) {
+		svc, _ := newTestAPIKeyService()
+		rawKey, _, err := svc.CreateAPIKey(userContext("admin-1", models.RoleAdmin), "batch", []string{listOrdersMethod}, 0)
+		require.NoError(t, err)
+
+		_, err = svc.Authenticate(context.Background(), rawKey, searchUsersMethod)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
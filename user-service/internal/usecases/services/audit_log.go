@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/clientinfo"
+	logger "user-service/internal/interfaces/logger"
+	"user-service/internal/interfaces/repositories"
+)
+
+const (
+	auditBatchSize     = 100
+	auditFlushInterval = time.Second
+	auditWriteTimeout  = 5 * time.Second
+)
+
+// AuditLog records audit events without blocking the caller: events are
+// queued and written in batches by a background goroutine. When the queue is
+// full, events are dropped and logged rather than slowing requests down.
+type AuditLog struct {
+	repo   repositories.AuditRepository
+	logger logger.Logger
+	events chan models.AuditEvent
+
+	// mu guards closed; Record holds it shared while queueing so that Close
+	// never closes events under a concurrent send.
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+func NewAuditLog(repo repositories.AuditRepository, logger logger.Logger, queueSize int) *AuditLog {
+	a := &AuditLog{
+		repo:   repo,
+		logger: logger,
+		events: make(chan models.AuditEvent, queueSize),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Record queues an event. The time, client address and, unless set, the
+// actor are taken from ctx. A nil or closed AuditLog discards events.
+func (a *AuditLog) Record(ctx context.Context, event models.AuditEvent) {
+	if a == nil {
+		return
+	}
+
+	event.OccurredAt = time.Now().UTC()
+	if event.IP == "" {
+		event.IP = clientinfo.IP(ctx)
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = clientinfo.UserAgent(ctx)
+	}
+	if event.Outcome == "" {
+		event.Outcome = models.AuditOutcomeSuccess
+	}
+	if event.ActorType == "" {
+		if principal, ok := auth.PrincipalFromContext(ctx); ok {
+			if principal.IsAPIKey() {
+				event.ActorType, event.ActorID = models.AuditActorAPIKey, principal.APIKeyID
+			} else {
+				event.ActorType, event.ActorID = models.AuditActorUser, principal.UserID
+			}
+		} else {
+			// unauthenticated calls such as logins act on the target themselves
+			event.ActorType, event.ActorID = models.AuditActorUser, event.TargetID
+		}
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		a.logger.Errorf("Audit log closed, dropped %s event for user %s", event.Action, event.TargetID)
+		return
+	}
+
+	select {
+	case a.events <- event:
+	default:
+		a.logger.Errorf("Audit queue full, dropped %s event for user %s", event.Action, event.TargetID)
+	}
+}
+
+// Close writes the queued events and stops the background writer. Events
+// recorded afterwards are dropped.
+func (a *AuditLog) Close() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+}
+
+func (a *AuditLog) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditEvent, 0, auditBatchSize)
+	for {
+		select {
+		case event, ok := <-a.events:
+			if !ok {
+				a.write(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= auditBatchSize {
+				a.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			a.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (a *AuditLog) write(batch []models.AuditEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), auditWriteTimeout)
+	defer cancel()
+
+	if err := a.repo.InsertEvents(ctx, batch); err != nil {
+		a.logger.Errorf("Failed to write %d audit events: %v", len(batch), err)
+	}
+}
+
+// ListAuditEvents returns a page of the audit events concerning a user,
+// newest first, and their total count. Users see their own events, admins
+// those of any user.
+func (a *AuditLog) ListAuditEvents(ctx context.Context, userID, action string, page, pageSize int) ([]models.AuditEvent, int64, error) {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultUserPageSize
+	}
+	if pageSize > maxUserPageSize {
+		pageSize = maxUserPageSize
+	}
+
+	events, total, err := a.repo.ListEvents(ctx, userID, action, int64((page-1)*pageSize), int64(pageSize))
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "failed to list audit events: %v", err)
+	}
+	return events, total, nil
+}
+
+// anonymize removes the personal data from the events of a purged account.
+func (a *AuditLog) anonymize(ctx context.Context, userID string) error {
+	if a == nil {
+		return nil
+	}
+
+	_, err := a.repo.AnonymizeEvents(ctx, userID)
+	return err
+}
+
+// eventsFor returns every event concerning a user, for data exports.
+func (a *AuditLog) eventsFor(ctx context.Context, userID string) ([]models.AuditEvent, error) {
+	if a == nil {
+		return []models.AuditEvent{}, nil
+	}
+
+	events, _, err := a.repo.ListEvents(ctx, userID, "", 0, 0)
+	return events, err
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+	stdlogger "user-service/internal/infrastructure/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Run("Writes in batches and flushes on close", func(t *testing.T) {
+		repo := &fakeAuditRepo{}
+		log := NewAuditLog(repo, &stdlogger.StdLogger{}, 1000)
+
+		for i := 0; i < 2*auditBatchSize+50; i++ {
+			log.Record(context.Background(), models.AuditEvent{Action: models.AuditLogin, TargetID: "user-1"})
+		}
+		log.Close()
+
+		assert.Len(t, repo.all(), 2*auditBatchSize+50)
+		assert.GreaterOrEqual(t, len(repo.batches), 3)
+		for _, size := range repo.batches {
+			assert.LessOrEqual(t, size, auditBatchSize)
+		}
+	})
+
+	t.Run("Flushes a partial batch after the interval", func(t *testing.T) {
+		repo := &fakeAuditRepo{}
+		log := NewAuditLog(repo, &stdlogger.StdLogger{}, 1000)
+		defer log.Close()
+
+		log.Record(userContext("user-1"), models.AuditEvent{Action: models.AuditLogin, TargetID: "user-1"})
+
+		require.Eventually(t, func() bool { return len(repo.all()) == 1 }, 3*auditFlushInterval, 10*time.Millisecond)
+		event := repo.all()[0]
+		assert.Equal(t, models.AuditActorUser, event.ActorType)
+		assert.Equal(t, "user-1", event.ActorID)
+		assert.Equal(t, models.AuditOutcomeSuccess, event.Outcome)
+	})
+
+	t.Run("Drops events when the queue is full", func(t *testing.T) {
+		// no writer, so the queue is never drained
+		log := &AuditLog{
+			repo:   &fakeAuditRepo{},
+			logger: &stdlogger.StdLogger{},
+			events: make(chan models.AuditEvent, 2),
+			done:   make(chan struct{}),
+		}
+
+		for i := 0; i < 5; i++ {
+			log.Record(context.Background(), models.AuditEvent{Action: models.AuditLogin, TargetID: "user-1"})
+		}
+		assert.Len(t, log.events, 2)
+	})
+
+	t.Run("Ignores events after close", func(t *testing.T) {
+		repo := &fakeAuditRepo{}
+		log := NewAuditLog(repo, &stdlogger.StdLogger{}, 1000)
+
+		log.Record(context.Background(), models.AuditEvent{Action: models.AuditLogin, TargetID: "user-1"})
+		log.Close()
+
+		assert.NotPanics(t, func() {
+			log.Record(context.Background(), models.AuditEvent{Action: models.AuditLogin, TargetID: "user-1"})
+			log.Close()
+		})
+		assert.Len(t, repo.all(), 1)
+	})
+
+	t.Run("Nil log discards events", func(t *testing.T) {
+		var log *AuditLog
+
+		assert.NotPanics(t, func() {
+			log.Record(context.Background(), models.AuditEvent{Action: models.AuditLogin})
+			log.Close()
+		})
+	})
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"user-service/internal/core/auth"
+	"user-service/internal/errors"
+)
+
+func authorizeUser(ctx context.Context, requestedID string) (string, error) {
+	userID, err := auth.ResolveUserID(ctx, requestedID)
+	switch err {
+	case nil:
+		return userID, nil
+	case errors.ErrUnauthenticated:
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	case errors.ErrUserIDRequired:
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+}
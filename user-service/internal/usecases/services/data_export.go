@@ -0,0 +1,142 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"time"
+	"user-service/internal/core/models"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZip  = "zip"
+)
+
+// ExportedProfile is the part of a user record handed out in a data export.
+// Fields are listed explicitly so that secrets such as the password hash or
+// the TOTP secret never end up in an export.
+type ExportedProfile struct {
+	ID               string                    `json:"id"`
+	Username         string                    `json:"username"`
+	Email            string                    `json:"email"`
+	Roles            []string                  `json:"roles"`
+	Status           string                    `json:"status"`
+	TwoFactorEnabled bool                      `json:"two_factor_enabled"`
+	Identities       []models.ExternalIdentity `json:"identities,omitempty"`
+	CreatedAt        time.Time                 `json:"created_at"`
+	UpdatedAt        time.Time                 `json:"updated_at"`
+}
+
+// UserDataExport is everything stored about a user, as returned for a data
+// subject access request.
+type UserDataExport struct {
+	ExportedAt  time.Time           `json:"exported_at"`
+	Profile     ExportedProfile     `json:"profile"`
+	Orders      []*models.Order     `json:"orders"`
+	Sessions    []models.Session    `json:"sessions"`
+	AuditEvents []models.AuditEvent `json:"audit_events"`
+}
+
+// ExportUserData collects the data of a user and writes it to w either as a
+// single JSON document or as a zip archive with one JSON file per section.
+func (u *UserService) ExportUserData(ctx context.Context, userID, format string, w io.Writer) error {
+	if format == "" {
+		format = ExportFormatJSON
+	}
+	if format != ExportFormatJSON && format != ExportFormatZip {
+		return status.Errorf(codes.InvalidArgument, "unsupported export format %q", format)
+	}
+
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	export, err := u.collectUserData(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	u.logger.Infof("Exporting data of user %s as %s", userID, format)
+
+	if format == ExportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	}
+
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"orders.json", export.Orders},
+		{"sessions.json", export.Sessions},
+		{"audit_events.json", export.AuditEvents},
+	}
+	for _, file := range files {
+		if err := writeZipJSON(archive, file.name, export.ExportedAt, file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (u *UserService) collectUserData(ctx context.Context, userID string) (*UserDataExport, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user.ID == "" {
+		return nil, status.Errorf(codes.NotFound, "user with id %s not found", userID)
+	}
+
+	orders, err := u.orderRepo.GetOrdersByUserID(ctx, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load orders: %v", err)
+	}
+	if orders == nil {
+		orders = []*models.Order{}
+	}
+
+	sessions, err := u.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditEvents, err := u.audit.eventsFor(ctx, userID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load audit events: %v", err)
+	}
+
+	return &UserDataExport{
+		ExportedAt: time.Now().UTC(),
+		Profile: ExportedProfile{
+			ID:               user.ID,
+			Username:         user.Username,
+			Email:            user.Email,
+			Roles:            user.Roles,
+			Status:           user.Status,
+			TwoFactorEnabled: user.TwoFactor.Enabled,
+			Identities:       user.Identities,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+		},
+		Orders:      orders,
+		Sessions:    sessions,
+		AuditEvents: auditEvents,
+	}, nil
+}
+
+func writeZipJSON(archive *zip.Writer, name string, modified time.Time, data interface{}) error {
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
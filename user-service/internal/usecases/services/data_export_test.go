@@ -0,0 +1,132 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newExportFixture stores a user with two-factor authentication, an order, a
+// session and an audit event.
+func newExportFixture(t *testing.T) *userServiceFixture {
+	t.Helper()
+
+	f := newUserServiceFixture(t)
+	user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+	require.NoError(t, f.users.UpdateTwoFactor(context.Background(), "user-1", models.TwoFactor{
+		Enabled:       true,
+		Secret:        "JBSWY3DPEHPK3PXP",
+		RecoveryCodes: []string{"recovery-code-hash"},
+		EnabledAt:     time.Now(),
+	}))
+
+	orders := newFakeOrderRepo()
+	require.NoError(t, orders.CreateOrder(context.Background(), &models.Order{OrderID: "order-1", UserID: "user-1", Status: models.OrderStatusPending}))
+	f.service.orderRepo = orders
+
+	_, err := f.service.IssueTokens(context.Background(), user)
+	require.NoError(t, err)
+	require.NoError(t, f.audit.InsertEvents(context.Background(), []models.AuditEvent{{
+		ID:       "event-1",
+		Action:   models.AuditLogin,
+		TargetID: "user-1",
+		Outcome:  models.AuditOutcomeSuccess,
+	}}))
+	return f
+}
+
+// assertNoSecrets fails if data contains the password hash, the TOTP secret
+// or the recovery codes of the user.
+func assertNoSecrets(t *testing.T, f *userServiceFixture, data []byte) {
+	t.Helper()
+
+	user := f.users.get("user-1")
+	assert.NotContains(t, string(data), `"password"`)
+	assert.NotContains(t, string(data), user.Password)
+	assert.NotContains(t, string(data), user.TwoFactor.Secret)
+	assert.NotContains(t, string(data), "recovery-code-hash")
+}
+
+func TestExportUserData(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		f := newExportFixture(t)
+
+		var buf bytes.Buffer
+		require.NoError(t, f.service.ExportUserData(userContext("user-1"), "user-1", ExportFormatJSON, &buf))
+		assertNoSecrets(t, f, buf.Bytes())
+
+		var export UserDataExport
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &export))
+		assert.Equal(t, "user-1", export.Profile.ID)
+		assert.True(t, export.Profile.TwoFactorEnabled)
+		require.Len(t, export.Orders, 1)
+		assert.Equal(t, "order-1", export.Orders[0].OrderID)
+		assert.Len(t, export.Sessions, 1)
+		require.Len(t, export.AuditEvents, 1)
+		assert.Equal(t, "event-1", export.AuditEvents[0].ID)
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		f := newExportFixture(t)
+
+		var buf bytes.Buffer
+		require.NoError(t, f.service.ExportUserData(userContext("user-1"), "user-1", ExportFormatZip, &buf))
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+
+		files := map[string][]byte{}
+		for _, file := range archive.File {
+			r, err := file.Open()
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			r.Close()
+			assertNoSecrets(t, f, data)
+			files[file.Name] = data
+		}
+		require.Len(t, files, 4)
+
+		var profile ExportedProfile
+		require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+		assert.Equal(t, "alice@example.com", profile.Email)
+
+		var orders []*models.Order
+		require.NoError(t, json.Unmarshal(files["orders.json"], &orders))
+		require.Len(t, orders, 1)
+		assert.Equal(t, "order-1", orders[0].OrderID)
+
+		var sessions []models.Session
+		require.NoError(t, json.Unmarshal(files["sessions.json"], &sessions))
+		assert.Len(t, sessions, 1)
+
+		var events []models.AuditEvent
+		require.NoError(t, json.Unmarshal(files["audit_events.json"], &events))
+		require.Len(t, events, 1)
+		assert.Equal(t, "event-1", events[0].ID)
+	})
+
+	t.Run("Users only export their own data", func(t *testing.T) {
+		f := newExportFixture(t)
+
+		err := f.service.ExportUserData(userContext("user-2"), "user-1", ExportFormatJSON, io.Discard)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Rejects unknown formats", func(t *testing.T) {
+		f := newExportFixture(t)
+
+		err := f.service.ExportUserData(userContext("user-1"), "user-1", "csv", io.Discard)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
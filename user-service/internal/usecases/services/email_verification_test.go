@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"user-service/internal/core/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func registerUser(t *testing.T, f *userServiceFixture, username, email string) models.User {
+	t.Helper()
+
+	user, err := f.service.RegisterUser(context.Background(), models.User{Username: username, Email: email, Password: "Password123"})
+	require.NoError(t, err)
+	return user
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Run("Verifies the registered address once", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := registerUser(t, f, "alice", "alice@example.com")
+		assert.False(t, f.users.get(user.ID).IsEmailVerified())
+
+		token := f.email.last("verification").token
+		require.NoError(t, f.service.VerifyEmail(context.Background(), token))
+		assert.True(t, f.users.get(user.ID).IsEmailVerified())
+
+		err := f.service.VerifyEmail(context.Background(), token)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Old token does not verify a changed address", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := registerUser(t, f, "mallory", "mallory@example.com")
+		oldToken := f.email.last("verification").token
+
+		_, err := f.service.UpdateProfile(userContext(user.ID), user.ID, "", "victim@example.com")
+		require.NoError(t, err)
+
+		err = f.service.VerifyEmail(context.Background(), oldToken)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.False(t, f.users.get(user.ID).IsEmailVerified())
+
+		newToken := f.email.last("verification")
+		assert.Equal(t, "victim@example.com", newToken.to)
+		require.NoError(t, f.service.VerifyEmail(context.Background(), newToken.token))
+		assert.True(t, f.users.get(user.ID).IsEmailVerified())
+	})
+
+	t.Run("Token is rejected if the address changed without a new token", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := registerUser(t, f, "mallory", "mallory@example.com")
+		token := f.email.last("verification").token
+
+		require.NoError(t, f.users.update(user.ID, func(u *models.User) { u.Email = "victim@example.com" }))
+
+		err := f.service.VerifyEmail(context.Background(), token)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.False(t, f.users.get(user.ID).IsEmailVerified())
+	})
+
+	t.Run("Resending replaces the previous link", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := registerUser(t, f, "alice", "alice@example.com")
+		first := f.email.last("verification").token
+
+		f.cache.Expire(emailVerificationThrottlePrefix + "alice@example.com")
+		require.NoError(t, f.service.ResendVerificationEmail(context.Background(), "alice@example.com"))
+		second := f.email.last("verification").token
+		require.NotEqual(t, first, second)
+
+		err := f.service.VerifyEmail(context.Background(), first)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.NoError(t, f.service.VerifyEmail(context.Background(), second))
+		assert.True(t, f.users.get(user.ID).IsEmailVerified())
+	})
+}
@@ -0,0 +1,736 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	apperrors "user-service/internal/errors"
+	"user-service/internal/infrastructure/cache/cachetest"
+	stdlogger "user-service/internal/infrastructure/logger"
+	"user-service/internal/infrastructure/utils/jwt"
+	"user-service/internal/infrastructure/utils/security"
+	"user-service/internal/infrastructure/utils/uuid"
+	"user-service/internal/interfaces/repositories"
+	"user-service/internal/usecases/validators"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepo is an in-memory UserRepository. Like the Mongo one, lookups
+// that find nothing return an empty user, except GetUserByID.
+type fakeUserRepo struct {
+	mu    sync.Mutex
+	users map[string]models.User
+}
+
+func newFakeUserRepo() *fakeUserRepo {
+	return &fakeUserRepo{users: map[string]models.User{}}
+}
+
+func (r *fakeUserRepo) add(user models.User) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = user
+	return user
+}
+
+func (r *fakeUserRepo) get(userID string) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.users[userID]
+}
+
+func (r *fakeUserRepo) update(userID string, change func(user *models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return errors.New("user not found")
+	}
+	change(&user)
+	user.UpdatedAt = time.Now()
+	r.users[userID] = user
+	return nil
+}
+
+func (r *fakeUserRepo) find(match func(user models.User) bool) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			return user
+		}
+	}
+	return models.User{}
+}
+
+func (r *fakeUserRepo) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	if existing := r.find(func(u models.User) bool { return strings.EqualFold(u.Email, user.Email) }); existing.ID != "" {
+		return models.User{}, errors.New("duplicate email")
+	}
+	if user.ID == "" {
+		user.ID = uuid.NewUUIDService().GenerateUUID()
+	}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	return r.add(user), nil
+}
+
+func (r *fakeUserRepo) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.find(func(u models.User) bool { return strings.EqualFold(u.Email, email) }), nil
+}
+
+func (r *fakeUserRepo) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	return r.find(func(u models.User) bool { return u.Username == username }), nil
+}
+
+func (r *fakeUserRepo) AuthenticateUser(ctx context.Context, email, password string) (models.User, error) {
+	return models.User{}, errors.New("not implemented")
+}
+
+func (r *fakeUserRepo) GetUserByID(ctx context.Context, userID string) (models.User, error) {
+	user := r.get(userID)
+	if user.ID == "" {
+		return models.User{}, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepo) GetUserByExternalIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	return r.find(func(u models.User) bool {
+		for _, identity := range u.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *fakeUserRepo) LinkExternalIdentity(ctx context.Context, userID string, identity models.ExternalIdentity) error {
+	if linked, _ := r.GetUserByExternalIdentity(ctx, identity.Provider, identity.Subject); linked.ID != "" {
+		return &apperrors.AlreadyExistsError{Field: "identity"}
+	}
+	return r.update(userID, func(user *models.User) {
+		user.Identities = append(user.Identities, identity)
+	})
+}
+
+func (r *fakeUserRepo) SoftDeleteUser(ctx context.Context, userID string, deletedAt time.Time) error {
+	return r.update(userID, func(user *models.User) {
+		user.DeletedAt = &deletedAt
+	})
+}
+
+func (r *fakeUserRepo) RestoreUser(ctx context.Context, userID string) (bool, error) {
+	restored := false
+	err := r.update(userID, func(user *models.User) {
+		if user.DeletedAt != nil && user.AnonymizedAt == nil {
+			user.DeletedAt = nil
+			restored = true
+		}
+	})
+	return restored, err
+}
+
+func (r *fakeUserRepo) GetUsersDeletedBefore(ctx context.Context, cutoff time.Time, limit int64) ([]models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []models.User
+	for _, user := range r.users {
+		if user.DeletedAt != nil && !user.DeletedAt.After(cutoff) && user.AnonymizedAt == nil && int64(len(users)) < limit {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) AnonymizeUser(ctx context.Context, userID string, anonymizedAt time.Time) error {
+	return r.update(userID, func(user *models.User) {
+		user.Username = "deleted-" + userID
+		user.Email = "deleted-" + userID + "@deleted.invalid"
+		user.Password = ""
+		user.Roles = []string{}
+		user.Status = models.UserStatusDeleted
+		user.TwoFactor = models.TwoFactor{}
+		user.Identities = nil
+		user.AnonymizedAt = &anonymizedAt
+	})
+}
+
+func (r *fakeUserRepo) SearchUsers(ctx context.Context, filter repositories.UserFilter, skip, limit int64) ([]models.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matches []models.User
+	for _, user := range r.users {
+		if !strings.HasPrefix(strings.ToLower(user.Email), strings.ToLower(filter.Email)) ||
+			!strings.HasPrefix(strings.ToLower(user.Username), strings.ToLower(filter.Username)) {
+			continue
+		}
+		if !filter.CreatedAfter.IsZero() && !user.CreatedAt.After(filter.CreatedAfter) {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() && !user.CreatedAt.Before(filter.CreatedBefore) {
+			continue
+		}
+		matches = append(matches, user)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+
+	total := int64(len(matches))
+	if skip > total {
+		skip = total
+	}
+	matches = matches[skip:]
+	if limit > 0 && int64(len(matches)) > limit {
+		matches = matches[:limit]
+	}
+	return matches, total, nil
+}
+
+func (r *fakeUserRepo) SetDisabled(ctx context.Context, userID string, disabled bool) error {
+	return r.update(userID, func(user *models.User) {
+		user.Disabled = disabled
+	})
+}
+
+func (r *fakeUserRepo) SetPasswordResetRequired(ctx context.Context, userID string) error {
+	return r.update(userID, func(user *models.User) {
+		user.PasswordResetRequired = true
+	})
+}
+
+func (r *fakeUserRepo) UpdateRoles(ctx context.Context, userID string, roles []string) error {
+	return r.update(userID, func(user *models.User) {
+		user.Roles = roles
+	})
+}
+
+func (r *fakeUserRepo) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateProfile(ctx context.Context, updated models.User) error {
+	return r.update(updated.ID, func(user *models.User) {
+		user.Username = updated.Username
+		user.Email = updated.Email
+		user.Status = updated.Status
+	})
+}
+
+func (r *fakeUserRepo) UpdatePassword(ctx context.Context, userID string, hashedPassword string) error {
+	return r.update(userID, func(user *models.User) {
+		user.Password = hashedPassword
+		user.PasswordResetRequired = false
+	})
+}
+
+func (r *fakeUserRepo) MarkEmailVerified(ctx context.Context, userID, email string) (bool, error) {
+	verified := false
+	err := r.update(userID, func(user *models.User) {
+		if strings.EqualFold(user.Email, email) {
+			user.Status = models.UserStatusActive
+			verified = true
+		}
+	})
+	return verified, err
+}
+
+func (r *fakeUserRepo) UpdateTwoFactor(ctx context.Context, userID string, twoFactor models.TwoFactor) error {
+	return r.update(userID, func(user *models.User) {
+		user.TwoFactor = twoFactor
+	})
+}
+
+func (r *fakeUserRepo) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	consumed := false
+	err := r.update(userID, func(user *models.User) {
+		for i, hash := range user.TwoFactor.RecoveryCodes {
+			if hash == codeHash {
+				user.TwoFactor.RecoveryCodes = append(user.TwoFactor.RecoveryCodes[:i:i], user.TwoFactor.RecoveryCodes[i+1:]...)
+				consumed = true
+				return
+			}
+		}
+	})
+	return consumed, err
+}
+
+// fakeAPIKeyRepo is an in-memory APIKeyRepository.
+type fakeAPIKeyRepo struct {
+	mu      sync.Mutex
+	keys    map[string]models.APIKey
+	touched int
+}
+
+func newFakeAPIKeyRepo() *fakeAPIKeyRepo {
+	return &fakeAPIKeyRepo{keys: map[string]models.APIKey{}}
+}
+
+func (r *fakeAPIKeyRepo) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.Hash == key.Hash {
+			return errors.New("duplicate hash")
+		}
+	}
+	r.keys[key.ID] = key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, apperrors.ErrAPIKeyNotFound
+}
+
+func (r *fakeAPIKeyRepo) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]models.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepo) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return apperrors.ErrAPIKeyNotFound
+	}
+	key.RevokedAt = &revokedAt
+	r.keys[id] = key
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.keys[id]
+	key.LastUsedAt = &usedAt
+	r.keys[id] = key
+	r.touched++
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// fakeAuditRepo is an in-memory AuditRepository that remembers the size of
+// every batch written.
+type fakeAuditRepo struct {
+	mu      sync.Mutex
+	events  []models.AuditEvent
+	batches []int
+}
+
+func (r *fakeAuditRepo) all() []models.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]models.AuditEvent(nil), r.events...)
+}
+
+func (r *fakeAuditRepo) InsertEvents(ctx context.Context, events []models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, events...)
+	r.batches = append(r.batches, len(events))
+	return nil
+}
+
+func (r *fakeAuditRepo) ListEvents(ctx context.Context, targetID, action string, skip, limit int64) ([]models.AuditEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []models.AuditEvent{}
+	for _, event := range r.events {
+		if event.TargetID == targetID && (action == "" || event.Action == action) {
+			events = append(events, event)
+		}
+	}
+	return events, int64(len(events)), nil
+}
+
+func (r *fakeAuditRepo) AnonymizeEvents(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changed int64
+	for i, event := range r.events {
+		if event.TargetID != userID && event.ActorID != userID {
+			continue
+		}
+		event.IP = ""
+		event.UserAgent = ""
+		if event.Details != nil {
+			delete(event.Details, "email")
+		}
+		r.events[i] = event
+		changed++
+	}
+	return changed, nil
+}
+
+func (r *fakeAuditRepo) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+// fakeProductRepo is an in-memory ProductRepository. reserveErrors makes
+// ReserveStock fail for the given products.
+type fakeProductRepo struct {
+	mu            sync.Mutex
+	products      map[string]models.Product
+	reserveErrors map[string]error
+	// releaseFailures is how many more releases of a product fail
+	releaseFailures map[string]int
+}
+
+func newFakeProductRepo() *fakeProductRepo {
+	return &fakeProductRepo{products: map[string]models.Product{}, reserveErrors: map[string]error{}, releaseFailures: map[string]int{}}
+}
+
+func (r *fakeProductRepo) stock(productID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.products[productID].Stock
+}
+
+func (r *fakeProductRepo) CreateProduct(ctx context.Context, product models.Product) (models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products[product.ID] = product
+	return product, nil
+}
+
+func (r *fakeProductRepo) GetProductByID(ctx context.Context, id string) (models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return models.Product{}, apperrors.ErrProductNotFound
+	}
+	return product, nil
+}
+
+func (r *fakeProductRepo) UpdateProduct(ctx context.Context, id string, product models.Product) (models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products[id] = product
+	return product, nil
+}
+
+func (r *fakeProductRepo) DeleteProduct(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.products, id)
+	return nil
+}
+
+func (r *fakeProductRepo) ListProducts(ctx context.Context, filter map[string]interface{}, skip, limit int64) ([]models.Product, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *fakeProductRepo) MigrateMoney(ctx context.Context, currency string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeProductRepo) ReserveStock(ctx context.Context, productID string, quantity int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reserveErrors[productID]; err != nil {
+		return false, err
+	}
+	product, ok := r.products[productID]
+	if !ok || product.Stock < quantity {
+		return false, nil
+	}
+	product.Stock -= quantity
+	r.products[productID] = product
+	return true, nil
+}
+
+func (r *fakeProductRepo) ReleaseStock(ctx context.Context, productID string, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.releaseFailures[productID] > 0 {
+		r.releaseFailures[productID]--
+		return errors.New("connection reset")
+	}
+	product, ok := r.products[productID]
+	if !ok {
+		return errors.New("product not found")
+	}
+	product.Stock += quantity
+	r.products[productID] = product
+	return nil
+}
+
+// fakeOrderRepo is an in-memory OrderRepository keyed by OrderID.
+type fakeOrderRepo struct {
+	mu     sync.Mutex
+	orders map[string]models.Order
+}
+
+func newFakeOrderRepo() *fakeOrderRepo {
+	return &fakeOrderRepo{orders: map[string]models.Order{}}
+}
+
+func (r *fakeOrderRepo) get(orderID string) models.Order {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.orders[orderID]
+}
+
+func (r *fakeOrderRepo) CreateOrder(ctx context.Context, order *models.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.orders[order.OrderID] = *order
+	return nil
+}
+
+func (r *fakeOrderRepo) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &order, nil
+}
+
+func (r *fakeOrderRepo) TransitionOrderStatus(ctx context.Context, id string, from string, change models.OrderStatusChange) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, ok := r.orders[id]
+	if !ok || order.Status != from {
+		return false, nil
+	}
+	order.Status = change.Status
+	order.UpdatedAt = change.ChangedAt
+	order.StatusHistory = append(order.StatusHistory, change)
+	r.orders[id] = order
+	return true, nil
+}
+
+func (r *fakeOrderRepo) GetExpiredReservations(ctx context.Context, before time.Time, limit int64) ([]*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var orders []*models.Order
+	for _, order := range r.orders {
+		if order.Status == models.OrderStatusPending && order.ReservationExpiresAt != nil && order.ReservationExpiresAt.Before(before) && int64(len(orders)) < limit {
+			order := order
+			orders = append(orders, &order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *fakeOrderRepo) GetOrdersByUserID(ctx context.Context, userID string) ([]*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var orders []*models.Order
+	for _, order := range r.orders {
+		if order.UserID == userID {
+			order := order
+			orders = append(orders, &order)
+		}
+	}
+	return orders, nil
+}
+
+func (r *fakeOrderRepo) MigrateMoney(ctx context.Context, currency string) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeOrderRepo) MigrateStatuses(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+type orderServiceFixture struct {
+	service  *OrderService
+	orders   *fakeOrderRepo
+	products *fakeProductRepo
+	cache    *cachetest.MemoryCache
+}
+
+func newOrderServiceFixture() *orderServiceFixture {
+	f := &orderServiceFixture{
+		orders:   newFakeOrderRepo(),
+		products: newFakeProductRepo(),
+		cache:    cachetest.NewMemoryCache(),
+	}
+	productService := NewProductService(f.products, &stdlogger.StdLogger{}, f.cache)
+	f.service = NewOrderService(f.orders, NewPriceCalculator(), uuid.NewUUIDService(), productService, f.cache, &stdlogger.StdLogger{}, 30*time.Minute)
+	return f
+}
+
+// addProduct stores a product priced in USD with stock units.
+func (f *orderServiceFixture) addProduct(id string, price float64, stock int) {
+	f.products.products[id] = models.Product{ID: id, Name: "Product " + id, Price: models.MoneyFromFloat(price, "USD"), Stock: stock}
+}
+
+type sentEmail struct {
+	kind  string
+	to    string
+	token string
+}
+
+// fakeEmail records the emails that would have been sent.
+type fakeEmail struct {
+	mu   sync.Mutex
+	sent []sentEmail
+}
+
+func (e *fakeEmail) record(kind, to, token string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.sent = append(e.sent, sentEmail{kind: kind, to: to, token: token})
+	return nil
+}
+
+// last returns the most recent email of kind, or an empty one.
+func (e *fakeEmail) last(kind string) sentEmail {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := len(e.sent) - 1; i >= 0; i-- {
+		if e.sent[i].kind == kind {
+			return e.sent[i]
+		}
+	}
+	return sentEmail{}
+}
+
+func (e *fakeEmail) count(kind string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n := 0
+	for _, email := range e.sent {
+		if email.kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (e *fakeEmail) SendWelcomeEmail(to string) error {
+	return e.record("welcome", to, "")
+}
+
+func (e *fakeEmail) SendPasswordResetEmail(to, token string, expiresIn time.Duration) error {
+	return e.record("password_reset", to, token)
+}
+
+func (e *fakeEmail) SendVerificationEmail(to, token string, expiresIn time.Duration) error {
+	return e.record("verification", to, token)
+}
+
+func (e *fakeEmail) SendAccountLockedEmail(to, unlockToken string, lockedFor time.Duration) error {
+	return e.record("account_locked", to, unlockToken)
+}
+
+func (e *fakeEmail) SendAccountDeletedEmail(to, restoreToken string, restorableFor time.Duration) error {
+	return e.record("account_deleted", to, restoreToken)
+}
+
+type userServiceFixture struct {
+	service *UserService
+	users   *fakeUserRepo
+	cache   *cachetest.MemoryCache
+	email   *fakeEmail
+	hash    security.PasswordHash
+	jwt     jwt.JWTService
+	audit   *fakeAuditRepo
+	log     *AuditLog
+}
+
+func newUserServiceFixture(t *testing.T) *userServiceFixture {
+	t.Helper()
+
+	keys, err := jwt.NewKeySet(jwt.AlgorithmEdDSA, "")
+	require.NoError(t, err)
+
+	f := &userServiceFixture{
+		users: newFakeUserRepo(),
+		cache: cachetest.NewMemoryCache(),
+		email: &fakeEmail{},
+		hash:  security.NewBcryptHashWithCost(bcrypt.MinCost),
+		audit: &fakeAuditRepo{},
+	}
+	f.jwt = jwt.NewJWTService(keys, "", f.cache)
+	f.log = NewAuditLog(f.audit, &stdlogger.StdLogger{}, 1000)
+	t.Cleanup(f.log.Close)
+	f.service = NewUserService(f.users, validators.NewUserValidator(validators.DefaultPasswordPolicy(), nil), f.hash, f.jwt,
+		uuid.NewUUIDService(), nil, f.cache, &stdlogger.StdLogger{}, f.email, auth.EmailVerificationOptional, 30*24*time.Hour, f.log)
+	return f
+}
+
+// addUser stores a verified customer with password.
+func (f *userServiceFixture) addUser(t *testing.T, id, email, password string) models.User {
+	t.Helper()
+
+	hash, err := f.hash.HashPassword(password)
+	require.NoError(t, err)
+
+	return f.users.add(models.User{
+		ID:       id,
+		Username: strings.SplitN(email, "@", 2)[0],
+		Email:    email,
+		Password: hash,
+		Roles:    []string{models.RoleCustomer},
+		Status:   models.UserStatusActive,
+	})
+}
+
+func userContext(userID string, roles ...string) context.Context {
+	if len(roles) == 0 {
+		roles = []string{models.RoleCustomer}
+	}
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Type: auth.PrincipalUser, UserID: userID, Roles: roles, EmailVerified: true})
+}
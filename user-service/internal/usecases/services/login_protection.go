@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"strings"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/security"
+)
+
+const (
+	loginFailuresPrefix   = "login_failures:"
+	loginFailuresIPPrefix = "login_failures_ip:"
+	loginLockPrefix       = "login_lock:"
+	loginLockIPPrefix     = "login_lock_ip:"
+	accountUnlockPrefix   = "account_unlock:"
+
+	loginFailureWindow      = 24 * time.Hour
+	maxAccountLoginFailures = 5
+	maxIPLoginFailures      = 20
+	baseLoginLockout        = time.Minute
+	maxLoginLockout         = 24 * time.Hour
+)
+
+// loginLockout doubles the lockout for every failure past the threshold, up
+// to maxLoginLockout.
+func loginLockout(failures, threshold int64) time.Duration {
+	lockout := baseLoginLockout
+	for i := threshold; i < failures && lockout < maxLoginLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLoginLockout {
+		lockout = maxLoginLockout
+	}
+	return lockout
+}
+
+func loginAccountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginLock rejects logins for a locked account or client address. Cache
+// errors are logged and do not block the login.
+func (u *UserService) checkLoginLock(account, ip string) error {
+	keys := []string{loginLockPrefix + account}
+	if ip != "" {
+		keys = append(keys, loginLockIPPrefix+ip)
+	}
+
+	for _, key := range keys {
+		ttl, err := u.cache.TTL(key)
+		if err != nil {
+			u.logger.Errorf("Failed to check login lock %s: %v", key, err)
+			continue
+		}
+		if ttl > 0 {
+			return status.Errorf(codes.ResourceExhausted, "too many failed login attempts, try again in %s", ttl.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against both the account and the
+// client address and locks whichever crossed its threshold. user is empty
+// when the email is not registered; the account counter is kept anyway so
+// that unknown and known addresses behave the same.
+func (u *UserService) recordLoginFailure(ctx context.Context, account, ip string, user models.User) {
+	failures, err := u.cache.IncrementWithTTL(loginFailuresPrefix+account, loginFailureWindow)
+	if err != nil {
+		u.logger.Errorf("Failed to record login failure: %v", err)
+	} else if failures >= maxAccountLoginFailures {
+		lockout := loginLockout(failures, maxAccountLoginFailures)
+		if err := u.cache.Set(loginLockPrefix+account, "1", lockout); err != nil {
+			u.logger.Errorf("Failed to lock account: %v", err)
+		}
+
+		if user.ID != "" {
+			u.logger.Infof("Login locked for user %s for %s after %d failed attempts", user.ID, lockout, failures)
+			u.audit.Record(ctx, models.AuditEvent{
+				Action:   models.AuditAccountLocked,
+				TargetID: user.ID,
+				Outcome:  models.AuditOutcomeFailure,
+				Reason:   "too many failed login attempts",
+				Details:  map[string]string{"failures": strconv.FormatInt(failures, 10), "lockout": lockout.String()},
+			})
+			if failures == maxAccountLoginFailures {
+				if err := u.sendAccountLockedEmail(user, lockout); err != nil {
+					u.logger.Errorf("Failed to send account locked email to user %s: %v", user.ID, err)
+				}
+			}
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+
+	failures, err = u.cache.IncrementWithTTL(loginFailuresIPPrefix+ip, loginFailureWindow)
+	if err != nil {
+		u.logger.Errorf("Failed to record login failure: %v", err)
+		return
+	}
+
+	if failures >= maxIPLoginFailures {
+		lockout := loginLockout(failures, maxIPLoginFailures)
+		if err := u.cache.Set(loginLockIPPrefix+ip, "1", lockout); err != nil {
+			u.logger.Errorf("Failed to lock client address: %v", err)
+		}
+		u.logger.Infof("Login locked for address %s for %s after %d failed attempts", ip, lockout, failures)
+	}
+}
+
+// clearLoginFailures resets the account counters after a successful login or
+// unlock. Address counters are left to expire, so that a valid login cannot be
+// used to reset guessing against other accounts.
+func (u *UserService) clearLoginFailures(account string) {
+	for _, key := range []string{loginFailuresPrefix + account, loginLockPrefix + account} {
+		if err := u.cache.Delete(key); err != nil {
+			u.logger.Errorf("Failed to clear login failures: %v", err)
+		}
+	}
+}
+
+func (u *UserService) sendAccountLockedEmail(user models.User, lockout time.Duration) error {
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := u.cache.Set(accountUnlockPrefix+security.HashToken(token), user.ID, maxLoginLockout); err != nil {
+		return err
+	}
+
+	return u.email.SendAccountLockedEmail(user.Email, token, lockout)
+}
+
+// UnlockAccount lifts a login lockout using the token from the lock
+// notification email.
+func (u *UserService) UnlockAccount(ctx context.Context, token string) error {
+	key := accountUnlockPrefix + security.HashToken(token)
+
+	userID, err := u.cache.GetAndDelete(key)
+	if err != nil || userID == "" {
+		return status.Error(codes.InvalidArgument, "invalid or expired unlock token")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	u.clearLoginFailures(loginAccountKey(user.Email))
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditAccountUnlocked, TargetID: userID})
+
+	u.logger.Infof("Login lock cleared for user %s", userID)
+	return nil
+}
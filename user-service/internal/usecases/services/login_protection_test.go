@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}})
+}
+
+func TestLoginLockout(t *testing.T) {
+	assert.Equal(t, time.Minute, loginLockout(5, 5))
+	assert.Equal(t, 2*time.Minute, loginLockout(6, 5))
+	assert.Equal(t, 8*time.Minute, loginLockout(8, 5))
+	assert.Equal(t, maxLoginLockout, loginLockout(100, 5))
+}
+
+func TestAuthenticateUserLockout(t *testing.T) {
+	t.Run("Locks the account after too many failures", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		for i := 0; i < maxAccountLoginFailures; i++ {
+			_, err := f.service.AuthenticateUser(context.Background(), "alice@example.com", "wrong")
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+
+		_, err := f.service.AuthenticateUser(context.Background(), "Alice@example.com", "Password123")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		ttl, err := f.cache.TTL(loginLockPrefix + "alice@example.com")
+		require.NoError(t, err)
+		assert.InDelta(t, time.Minute.Seconds(), ttl.Seconds(), 1)
+
+		failures, err := f.cache.TTL(loginFailuresPrefix + "alice@example.com")
+		require.NoError(t, err)
+		assert.True(t, failures > 0, "the failure counter must expire")
+	})
+
+	t.Run("Backs off exponentially", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		for i := 0; i < maxAccountLoginFailures+2; i++ {
+			f.service.recordLoginFailure(context.Background(), "alice@example.com", "", user)
+		}
+
+		ttl, err := f.cache.TTL(loginLockPrefix + "alice@example.com")
+		require.NoError(t, err)
+		assert.InDelta(t, (4 * time.Minute).Seconds(), ttl.Seconds(), 1)
+	})
+
+	t.Run("Sends a single unlock email that lifts the lock", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		for i := 0; i < maxAccountLoginFailures+3; i++ {
+			_, _ = f.service.AuthenticateUser(context.Background(), "alice@example.com", "wrong")
+		}
+		assert.Equal(t, 1, f.email.count("account_locked"))
+
+		token := f.email.last("account_locked").token
+		require.NoError(t, f.service.UnlockAccount(context.Background(), token))
+
+		_, err := f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.NoError(t, err)
+
+		err = f.service.UnlockAccount(context.Background(), token)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Audits locks and unlocks", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		ctx := peerContext("203.0.113.7")
+
+		for i := 0; i < maxAccountLoginFailures; i++ {
+			_, _ = f.service.AuthenticateUser(ctx, "alice@example.com", "wrong")
+		}
+		require.NoError(t, f.service.UnlockAccount(ctx, f.email.last("account_locked").token))
+
+		f.log.Close()
+		var locked, unlocked []models.AuditEvent
+		for _, event := range f.audit.all() {
+			switch event.Action {
+			case models.AuditAccountLocked:
+				locked = append(locked, event)
+			case models.AuditAccountUnlocked:
+				unlocked = append(unlocked, event)
+			}
+		}
+		require.Len(t, locked, 1)
+		assert.Equal(t, "user-1", locked[0].TargetID)
+		assert.Equal(t, "203.0.113.7", locked[0].IP)
+		assert.Equal(t, models.AuditOutcomeFailure, locked[0].Outcome)
+		assert.Equal(t, "5", locked[0].Details["failures"])
+		require.Len(t, unlocked, 1)
+		assert.Equal(t, "user-1", unlocked[0].TargetID)
+	})
+
+	t.Run("Unknown emails are counted like known ones", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+
+		for i := 0; i < maxAccountLoginFailures; i++ {
+			_, _ = f.service.AuthenticateUser(context.Background(), "nobody@example.com", "wrong")
+		}
+
+		_, err := f.service.AuthenticateUser(context.Background(), "nobody@example.com", "wrong")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Equal(t, 0, f.email.count("account_locked"))
+	})
+
+	t.Run("Locks a client address guessing across accounts", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		ctx := peerContext("203.0.113.7")
+
+		for i := 0; i < maxIPLoginFailures; i++ {
+			_, _ = f.service.AuthenticateUser(ctx, fmt.Sprintf("guess-%d@example.com", i), "wrong")
+		}
+
+		_, err := f.service.AuthenticateUser(ctx, "alice@example.com", "Password123")
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		_, err = f.service.AuthenticateUser(peerContext("198.51.100.1"), "alice@example.com", "Password123")
+		assert.NoError(t, err)
+	})
+
+	t.Run("A successful login resets the account counter", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		for i := 0; i < maxAccountLoginFailures-1; i++ {
+			_, _ = f.service.AuthenticateUser(context.Background(), "alice@example.com", "wrong")
+		}
+		_, err := f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		require.NoError(t, err)
+
+		_, _ = f.service.AuthenticateUser(context.Background(), "alice@example.com", "wrong")
+		_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"regexp"
+	"strings"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/cache"
+	"user-service/internal/infrastructure/oidc"
+	"user-service/internal/infrastructure/utils/security"
+	"user-service/internal/infrastructure/utils/uuid"
+	logger "user-service/internal/interfaces/logger"
+	"user-service/internal/interfaces/repositories"
+)
+
+const (
+	oidcStatePrefix = "oidc_state:"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// OIDCLoginService signs users in through external OpenID Connect providers.
+// Provider accounts are linked to local users by subject, or on first login by
+// verified email address.
+type OIDCLoginService struct {
+	providers     map[string]*oidc.Provider
+	userRepo      repositories.UserRepository
+	cache         cache.CacheService
+	uuidGenerator uuid.Generator
+	logger        logger.Logger
+	audit         *AuditLog
+}
+
+func NewOIDCLoginService(providers []*oidc.Provider, userRepo repositories.UserRepository, cache cache.CacheService,
+	uuidGenerator uuid.Generator, logger logger.Logger, audit *AuditLog) *OIDCLoginService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &OIDCLoginService{
+		providers:     byName,
+		userRepo:      userRepo,
+		cache:         cache,
+		uuidGenerator: uuidGenerator,
+		logger:        logger,
+		audit:         audit,
+	}
+}
+
+// StartLogin returns the provider URL to redirect the user to and the state
+// that comes back with the callback.
+func (s *OIDCLoginService) StartLogin(ctx context.Context, providerName string) (authURL, state string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", status.Errorf(codes.InvalidArgument, "unknown identity provider %q", providerName)
+	}
+
+	state, err = security.GenerateToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := security.GenerateToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := json.Marshal(oidcLoginState{Provider: providerName, Nonce: nonce, CodeVerifier: verifier})
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.cache.Set(oidcStatePrefix+security.HashToken(state), string(data), oidcStateTTL); err != nil {
+		return "", "", err
+	}
+
+	return provider.AuthCodeURL(state, nonce, verifier), state, nil
+}
+
+// CompleteLogin handles the provider callback and returns the local user,
+// creating or linking it when needed.
+func (s *OIDCLoginService) CompleteLogin(ctx context.Context, state, code string) (models.User, error) {
+	key := oidcStatePrefix + security.HashToken(state)
+
+	data, err := s.cache.GetAndDelete(key)
+	if err != nil || data == "" {
+		return models.User{}, status.Error(codes.InvalidArgument, "invalid or expired login state")
+	}
+
+	var loginState oidcLoginState
+	if err := json.Unmarshal([]byte(data), &loginState); err != nil {
+		return models.User{}, status.Error(codes.InvalidArgument, "invalid or expired login state")
+	}
+
+	provider, ok := s.providers[loginState.Provider]
+	if !ok {
+		return models.User{}, status.Errorf(codes.InvalidArgument, "unknown identity provider %q", loginState.Provider)
+	}
+
+	idToken, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		s.logger.Errorf("OIDC code exchange with %s failed: %v", provider.Name(), err)
+		return models.User{}, status.Error(codes.Unauthenticated, "failed to sign in with the identity provider")
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, idToken, loginState.Nonce)
+	if err != nil {
+		s.logger.Errorf("OIDC ID token from %s rejected: %v", provider.Name(), err)
+		return models.User{}, status.Error(codes.Unauthenticated, "failed to sign in with the identity provider")
+	}
+
+	user, err := s.resolveUser(ctx, provider.Name(), claims)
+	if err == nil {
+		err = checkAdminTwoFactor(user)
+	}
+	if err != nil {
+		s.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditLogin,
+			TargetID: user.ID,
+			Outcome:  models.AuditOutcomeFailure,
+			Reason:   status.Convert(err).Message(),
+			Details:  map[string]string{"method": "oidc", "provider": provider.Name()},
+		})
+		return models.User{}, err
+	}
+
+	// with two-factor authentication the login completes in VerifySecondFactor
+	if !user.TwoFactor.Enabled {
+		s.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditLogin,
+			TargetID: user.ID,
+			Details:  map[string]string{"method": "oidc", "provider": provider.Name()},
+		})
+	}
+	return user, nil
+}
+
+func (s *OIDCLoginService) resolveUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims) (models.User, error) {
+	user, err := s.userRepo.GetUserByExternalIdentity(ctx, providerName, claims.Subject)
+	if err != nil {
+		return models.User{}, err
+	}
+	if user.ID != "" {
+		if user.IsDeleted() {
+			return models.User{}, errAccountDeleted
+		}
+		if user.Disabled {
+			return models.User{}, errAccountDisabled
+		}
+		return user, nil
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return models.User{}, status.Error(codes.FailedPrecondition, "the identity provider did not confirm your email address")
+	}
+
+	identity := models.ExternalIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+		LinkedAt: time.Now(),
+	}
+
+	user, err = s.userRepo.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if user.ID != "" {
+		if user.IsDeleted() {
+			return models.User{}, errAccountDeleted
+		}
+		if user.Disabled {
+			return models.User{}, errAccountDisabled
+		}
+
+		// linking to an unverified account would hand it to whoever registered
+		// the address first
+		if !user.IsEmailVerified() {
+			return models.User{}, status.Error(codes.FailedPrecondition, "an account with this email exists but is not verified, verify it before signing in with an identity provider")
+		}
+
+		if err := s.userRepo.LinkExternalIdentity(ctx, user.ID, identity); err != nil {
+			return models.User{}, err
+		}
+		if err := s.cache.Delete(fmt.Sprintf("user_profile:%s", user.ID)); err != nil {
+			s.logger.Errorf("Failed to invalidate profile cache for user %s: %v", user.ID, err)
+		}
+
+		s.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditIdentityLinked,
+			TargetID: user.ID,
+			Details:  map[string]string{"provider": providerName},
+		})
+		s.logger.Infof("Linked %s identity to user %s", providerName, user.ID)
+		user.Identities = append(user.Identities, identity)
+		return user, nil
+	}
+
+	username, err := s.availableUsername(ctx, claims.Email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	now := time.Now()
+	created, err := s.userRepo.CreateUser(ctx, models.User{
+		ID:         s.uuidGenerator.GenerateUUID(),
+		Username:   username,
+		Email:      claims.Email,
+		Roles:      []string{models.RoleCustomer},
+		Status:     models.UserStatusActive,
+		Identities: []models.ExternalIdentity{identity},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.logger.Infof("Created user %s from %s identity", created.ID, providerName)
+	return created, nil
+}
+
+// availableUsername derives a username from the local part of an email and
+// appends a random suffix when it is taken.
+func (s *OIDCLoginService) availableUsername(ctx context.Context, email string) (string, error) {
+	base := nonAlphanumeric.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "")
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		existing, err := s.userRepo.GetUserByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing.ID == "" {
+			return candidate, nil
+		}
+		candidate = base + strings.ReplaceAll(s.uuidGenerator.GenerateUUID(), "-", "")[:6]
+	}
+
+	return "", status.Error(codes.Internal, "failed to find an available username")
+}
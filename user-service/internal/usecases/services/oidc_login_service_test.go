@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"user-service/internal/core/models"
+	stdlogger "user-service/internal/infrastructure/logger"
+	"user-service/internal/infrastructure/oidc"
+	"user-service/internal/infrastructure/utils/uuid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestOIDCLoginService(f *userServiceFixture) *OIDCLoginService {
+	return NewOIDCLoginService(nil, f.users, f.cache, uuid.NewUUIDService(), &stdlogger.StdLogger{}, f.log)
+}
+
+func TestResolveUser(t *testing.T) {
+	t.Run("Finds the account linked to the identity", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		require.NoError(t, f.users.LinkExternalIdentity(context.Background(), "user-1", models.ExternalIdentity{Provider: "google", Subject: "sub-1"}))
+		svc := newTestOIDCLoginService(f)
+
+		// the provider address no longer matters once the identity is linked
+		user, err := svc.resolveUser(context.Background(), "google", &oidc.IDTokenClaims{Subject: "sub-1", Email: "other@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+	})
+
+	t.Run("Links a verified account with the same email", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		svc := newTestOIDCLoginService(f)
+
+		user, err := svc.resolveUser(context.Background(), "google", &oidc.IDTokenClaims{Subject: "sub-1", Email: "Alice@Example.com", EmailVerified: true})
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", user.ID)
+
+		identities := f.users.get("user-1").Identities
+		require.Len(t, identities, 1)
+		assert.Equal(t, "google", identities[0].Provider)
+		assert.Equal(t, "sub-1", identities[0].Subject)
+
+		linked, err := f.users.GetUserByExternalIdentity(context.Background(), "google", "sub-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", linked.ID)
+	})
+
+	t.Run("Does not link an unverified account", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		require.NoError(t, f.users.update("user-1", func(user *models.User) { user.Status = models.UserStatusUnverified }))
+		svc := newTestOIDCLoginService(f)
+
+		_, err := svc.resolveUser(context.Background(), "google", &oidc.IDTokenClaims{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Empty(t, f.users.get("user-1").Identities)
+	})
+
+	t.Run("Requires an email verified by the provider", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		svc := newTestOIDCLoginService(f)
+
+		_, err := svc.resolveUser(context.Background(), "google", &oidc.IDTokenClaims{Subject: "sub-1", Email: "alice@example.com"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Empty(t, f.users.get("user-1").Identities)
+	})
+
+	t.Run("Rejects disabled and deleted accounts", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		f.addUser(t, "user-2", "bob@example.com", "Password123")
+		require.NoError(t, f.users.LinkExternalIdentity(context.Background(), "user-1", models.ExternalIdentity{Provider: "google", Subject: "sub-1"}))
+		require.NoError(t, f.users.SetDisabled(context.Background(), "user-1", true))
+		require.NoError(t, f.users.SoftDeleteUser(context.Background(), "user-2", f.users.get("user-2").CreatedAt))
+		svc := newTestOIDCLoginService(f)
+
+		_, err := svc.resolveUser(context.Background(), "google", &oidc.IDTokenClaims{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = svc.resolveUser(context.Background(), "google", &oidc.IDTokenClaims{Subject: "sub-2", Email: "bob@example.com", EmailVerified: true})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Empty(t, f.users.get("user-2").Identities)
+	})
+
+	t.Run("Creates an account for a new email", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "carol@example.com", "Password123")
+		svc := newTestOIDCLoginService(f)
+
+		user, err := svc.resolveUser(context.Background(), "github", &oidc.IDTokenClaims{Subject: "sub-9", Email: "carol@example.org", EmailVerified: true})
+		require.NoError(t, err)
+		assert.NotEqual(t, "user-1", user.ID)
+		assert.Equal(t, "carol@example.org", user.Email)
+		assert.NotEqual(t, "carol", user.Username, "the taken username gets a suffix")
+		assert.Equal(t, []string{models.RoleCustomer}, user.Roles)
+		assert.True(t, user.IsEmailVerified())
+
+		again, err := svc.resolveUser(context.Background(), "github", &oidc.IDTokenClaims{Subject: "sub-9", Email: "carol@example.org", EmailVerified: true})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, again.ID)
+	})
+
+	t.Run("An identity is linked to one account only", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		f.addUser(t, "user-2", "bob@example.com", "Password123")
+		require.NoError(t, f.users.LinkExternalIdentity(context.Background(), "user-1", models.ExternalIdentity{Provider: "google", Subject: "sub-1"}))
+
+		err := f.users.LinkExternalIdentity(context.Background(), "user-2", models.ExternalIdentity{Provider: "google", Subject: "sub-1"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"time"
+	"user-service/internal/core/models"
+)
+
+const (
+	reservationReaperLease = "order_reservation_reaper_lease"
+	reservationBatchSize   = 100
+)
+
+// ExpireReservations cancels the pending orders whose reservation expired and
+// returns their stock. It returns how many orders were cancelled. Orders that
+// get paid or cancelled concurrently are skipped, so running it on several
+// replicas at once never releases stock twice.
+func (s *OrderService) ExpireReservations(ctx context.Context) (int, error) {
+	cancelled := 0
+
+	for {
+		orders, err := s.orderRepo.GetExpiredReservations(ctx, time.Now(), reservationBatchSize)
+		if err != nil {
+			return cancelled, err
+		}
+
+		for _, order := range orders {
+			updated, err := s.transitionOrder(ctx, order, models.OrderStatusCancelled, models.OrderActorSystem)
+			if err != nil {
+				return cancelled, err
+			}
+			if updated {
+				cancelled++
+			}
+		}
+
+		if len(orders) < reservationBatchSize {
+			return cancelled, nil
+		}
+	}
+}
+
+// StartReservationReaper blocks and expires reservations every interval. The
+// replicas share a lease in the cache so that only one of them does the work
+// in each interval.
+func (s *OrderService) StartReservationReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			acquired, err := s.cache.SetIfAbsent(reservationReaperLease, time.Now().UTC().Format(time.RFC3339), interval)
+			if err != nil {
+				s.logger.Errorf("Failed to acquire reservation reaper lease: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+
+			cancelled, err := s.ExpireReservations(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to expire order reservations: %v", err)
+			}
+			if cancelled > 0 {
+				s.logger.Infof("Cancelled %d orders with expired reservations", cancelled)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expireReservation moves the reservation deadline of an order into the past.
+func (f *orderServiceFixture) expireReservation(orderID string) {
+	f.orders.mu.Lock()
+	defer f.orders.mu.Unlock()
+
+	order := f.orders.orders[orderID]
+	expired := time.Now().Add(-time.Minute)
+	order.ReservationExpiresAt = &expired
+	f.orders.orders[orderID] = order
+}
+
+func (f *orderServiceFixture) setStatus(orderID, status string) {
+	f.orders.mu.Lock()
+	defer f.orders.mu.Unlock()
+
+	order := f.orders.orders[orderID]
+	order.Status = status
+	f.orders.orders[orderID] = order
+}
+
+func TestExpireReservations(t *testing.T) {
+	f := newOrderServiceFixture()
+	f.addProduct("product-1", 10, 10)
+	ctx := userContext("user-1")
+
+	expired, err := f.service.CreateOrder(ctx, "", []OrderLine{{ProductID: "product-1", Quantity: 2}})
+	require.NoError(t, err)
+	active, err := f.service.CreateOrder(ctx, "", []OrderLine{{ProductID: "product-1", Quantity: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, 5, f.products.stock("product-1"))
+
+	f.expireReservation(expired.OrderID)
+
+	cancelled, err := f.service.ExpireReservations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, cancelled)
+
+	order := f.orders.get(expired.OrderID)
+	assert.Equal(t, models.OrderStatusCancelled, order.Status)
+	assert.Equal(t, models.OrderActorSystem, order.StatusHistory[len(order.StatusHistory)-1].Actor)
+	assert.Equal(t, models.OrderStatusPending, f.orders.get(active.OrderID).Status)
+	assert.Equal(t, 7, f.products.stock("product-1"))
+
+	cancelled, err = f.service.ExpireReservations(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, cancelled)
+	assert.Equal(t, 7, f.products.stock("product-1"))
+}
+
+func TestReservationReaperLease(t *testing.T) {
+	run := func(f *orderServiceFixture) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		f.service.StartReservationReaper(ctx, 10*time.Millisecond)
+	}
+
+	t.Run("Skips while another replica holds the lease", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("product-1", 10, 10)
+		order, err := f.service.CreateOrder(userContext("user-1"), "", []OrderLine{{ProductID: "product-1", Quantity: 2}})
+		require.NoError(t, err)
+		f.expireReservation(order.OrderID)
+
+		require.NoError(t, f.cache.Set(reservationReaperLease, "other-replica", time.Hour))
+		run(f)
+
+		assert.Equal(t, models.OrderStatusPending, f.orders.get(order.OrderID).Status)
+		assert.Equal(t, 8, f.products.stock("product-1"))
+	})
+
+	t.Run("Expires reservations once it holds the lease", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("product-1", 10, 10)
+		order, err := f.service.CreateOrder(userContext("user-1"), "", []OrderLine{{ProductID: "product-1", Quantity: 2}})
+		require.NoError(t, err)
+		f.expireReservation(order.OrderID)
+
+		run(f)
+
+		assert.Equal(t, models.OrderStatusCancelled, f.orders.get(order.OrderID).Status)
+		assert.Equal(t, 10, f.products.stock("product-1"))
+	})
+}
+
+func TestUpdateOrderReleasesStock(t *testing.T) {
+	admin := userContext("admin-1", models.RoleAdmin)
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		released bool
+	}{
+		{name: "Cancelling a pending order", from: models.OrderStatusPending, to: models.OrderStatusCancelled, released: true},
+		{name: "Cancelling a paid order", from: models.OrderStatusPaid, to: models.OrderStatusCancelled, released: true},
+		{name: "Refunding a paid order", from: models.OrderStatusPaid, to: models.OrderStatusRefunded, released: true},
+		{name: "Refunding a fulfilling order", from: models.OrderStatusFulfilling, to: models.OrderStatusRefunded, released: true},
+		{name: "Refunding a shipped order", from: models.OrderStatusShipped, to: models.OrderStatusRefunded, released: false},
+		{name: "Paying an order", from: models.OrderStatusPending, to: models.OrderStatusPaid, released: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderServiceFixture()
+			f.addProduct("product-1", 10, 10)
+			order, err := f.service.CreateOrder(userContext("user-1"), "", []OrderLine{{ProductID: "product-1", Quantity: 4}})
+			require.NoError(t, err)
+			f.setStatus(order.OrderID, tt.from)
+
+			require.NoError(t, f.service.UpdateOrder(admin, order.OrderID, tt.to))
+
+			if tt.released {
+				assert.Equal(t, 10, f.products.stock("product-1"))
+			} else {
+				assert.Equal(t, 6, f.products.stock("product-1"))
+			}
+		})
+	}
+
+	t.Run("Orders without a reservation release nothing", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("product-1", 10, 10)
+		require.NoError(t, f.orders.CreateOrder(context.Background(), &models.Order{
+			OrderID: "legacy-1",
+			UserID:  "user-1",
+			Status:  models.OrderStatusPending,
+			Items:   []models.OrderItem{{ProductID: "product-1", Quantity: 4}},
+		}))
+
+		require.NoError(t, f.service.UpdateOrder(userContext("user-1"), "legacy-1", models.OrderStatusCancelled))
+		assert.Equal(t, 10, f.products.stock("product-1"))
+	})
+
+	t.Run("Concurrent cancellations release once", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("product-1", 10, 10)
+		order, err := f.service.CreateOrder(userContext("user-1"), "", []OrderLine{{ProductID: "product-1", Quantity: 4}})
+		require.NoError(t, err)
+
+		stale := *order
+		require.NoError(t, f.service.UpdateOrder(userContext("user-1"), order.OrderID, models.OrderStatusCancelled))
+
+		updated, err := f.service.transitionOrder(context.Background(), &stale, models.OrderStatusCancelled, models.OrderActorSystem)
+		require.NoError(t, err)
+		assert.False(t, updated)
+		assert.Equal(t, 10, f.products.stock("product-1"))
+	})
+}
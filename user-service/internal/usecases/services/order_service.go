@@ -3,20 +3,30 @@ package services
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	inventorypb "proto/generated/ecommerce/inventory"
 	orderpb "proto/generated/ecommerce/order"
 	"time"
+	"user-service/internal/core/auth"
 	"user-service/internal/core/models"
+	"user-service/internal/errors"
 	"user-service/internal/infrastructure/cache"
 	"user-service/internal/infrastructure/utils/uuid"
 	logger "user-service/internal/interfaces/logger"
 	"user-service/internal/interfaces/repositories"
 )
 
+// OrderLine is a product and quantity requested by a client. ExpectedPrice is
+// the unit price the client showed, in major units of the product currency;
+// zero means the client did not send one.
+type OrderLine struct {
+	ProductID     string
+	Quantity      int
+	ExpectedPrice float64
+}
+
 type OrderService struct {
 	orderRepo       repositories.OrderRepository
 	productService  *ProductService
@@ -24,9 +34,11 @@ type OrderService struct {
 	uuidGenerator   *uuid.Service
 	cache           cache.CacheService
 	logger          logger.Logger
+	reservationTTL  time.Duration
 }
 
-func NewOrderService(orderRepo repositories.OrderRepository, priceCalculator PriceCalculator, uuidGenerator *uuid.Service, ProductService *ProductService, cache cache.CacheService, logger logger.Logger) *OrderService {
+func NewOrderService(orderRepo repositories.OrderRepository, priceCalculator PriceCalculator, uuidGenerator *uuid.Service, ProductService *ProductService, cache cache.CacheService, logger logger.Logger,
+	reservationTTL time.Duration) *OrderService {
 	return &OrderService{
 		orderRepo:       orderRepo,
 		productService:  ProductService,
@@ -34,24 +46,62 @@ func NewOrderService(orderRepo repositories.OrderRepository, priceCalculator Pri
 		uuidGenerator:   uuidGenerator,
 		cache:           cache,
 		logger:          logger,
+		reservationTTL:  reservationTTL,
 	}
 }
 
-func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []models.OrderItem, status string) (*models.Order, error) {
-	totalPrice := s.priceCalculator.CalculateTotalPrice(items)
+// CreateOrder prices the items from the catalog, reserves their stock and
+// creates a pending order for userID. Nothing is reserved when the order
+// cannot be created, and the reservation is released if the order is still
+// unpaid after reservationTTL.
+func (s *OrderService) CreateOrder(ctx context.Context, userID string, lines []OrderLine) (*models.Order, error) {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "an order needs at least one item")
+	}
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "quantity for %s must be > 0", line.ProductID)
+		}
+	}
+
+	items, err := s.priceItems(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPrice, err := s.priceCalculator.CalculateTotalPrice(items)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to price the order: %v", err)
+	}
+	now := time.Now()
+	expiresAt := now.Add(s.reservationTTL)
 
 	order := &models.Order{
 		ID:         s.uuidGenerator.GenerateUUID(),
 		OrderID:    s.uuidGenerator.GenerateUUID(),
 		UserID:     userID,
-		Status:     status,
+		Status:     models.OrderStatusPending,
 		Items:      items,
 		TotalPrice: totalPrice,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		StatusHistory: []models.OrderStatusChange{
+			{Status: models.OrderStatusPending, ChangedAt: now, Actor: orderActor(ctx)},
+		},
+		ReservationExpiresAt: &expiresAt,
+	}
+
+	if err := s.productService.ReserveStock(ctx, items); err != nil {
+		return nil, err
 	}
 
 	if err := s.orderRepo.CreateOrder(ctx, order); err != nil {
+		s.productService.releaseAfterFailure(items)
 		return nil, err
 	}
 
@@ -59,33 +109,54 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID string, items []m
 }
 
 func (s *OrderService) CreateOrderFromProto(ctx context.Context, req *orderpb.CreateOrderRequest) (*models.Order, error) {
-	var items []models.OrderItem
+	if req.GetStatus() != "" && req.GetStatus() != models.OrderStatusPending {
+		return nil, status.Error(codes.InvalidArgument, "new orders always start as pending")
+	}
+
+	var lines []OrderLine
 
 	for _, item := range req.GetItems() {
-		if item.GetQuantity() <= 0 {
-			return nil, status.Errorf(codes.InvalidArgument, "quantity for %s must be > 0", item.GetProductId())
-		}
-		if item.GetPricePerUnit() <= 0 {
-			return nil, status.Errorf(codes.InvalidArgument, "price per unit for %s must be > 0", item.GetProductId())
-		}
+		lines = append(lines, OrderLine{
+			ProductID:     item.GetProductId(),
+			Quantity:      int(item.GetQuantity()),
+			ExpectedPrice: item.GetPricePerUnit(),
+		})
+	}
+
+	return s.CreateOrder(ctx, req.GetUserId(), lines)
+}
 
-		stock, err := s.CheckProductStock(ctx, item.GetProductId(), int(item.GetQuantity()))
+// priceItems turns lines into order items with the catalog price and name of
+// their product. An expected price is optional; when it does not match the
+// catalog the order is rejected so that the customer never pays an amount
+// they did not see.
+func (s *OrderService) priceItems(ctx context.Context, lines []OrderLine) ([]models.OrderItem, error) {
+	items := make([]models.OrderItem, 0, len(lines))
+	for _, line := range lines {
+		product, err := s.productService.GetProductByID(ctx, line.ProductID)
+		if err == errors.ErrProductNotFound {
+			return nil, status.Errorf(codes.NotFound, "product %s not found", line.ProductID)
+		}
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "stock check failed: %v", err)
+			return nil, err
 		}
 
-		if !stock.InStock {
-			return nil, status.Errorf(codes.FailedPrecondition, "product %s is out of stock (%d available)", item.GetProductId(), stock.AvailableStock)
+		if line.ExpectedPrice != 0 {
+			expected := models.MoneyFromFloat(line.ExpectedPrice, product.Price.Currency)
+			if expected != product.Price {
+				s.logger.Infof("Rejected order item %s priced %s by the client, catalog price is %s", line.ProductID, expected, product.Price)
+				return nil, status.Errorf(codes.FailedPrecondition, "the price of product %s is %s, not %s", line.ProductID, product.Price, expected)
+			}
 		}
 
 		items = append(items, models.OrderItem{
-			ProductID:    item.GetProductId(),
-			Quantity:     int(item.GetQuantity()),
-			PricePerUnit: item.GetPricePerUnit(),
+			ProductID:    line.ProductID,
+			ProductName:  product.Name,
+			Quantity:     line.Quantity,
+			PricePerUnit: product.Price,
 		})
 	}
-
-	return s.CreateOrder(ctx, req.GetUserId(), items, req.GetStatus())
+	return items, nil
 }
 
 func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
@@ -101,6 +172,9 @@ func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*models.Ord
 		err := json.Unmarshal([]byte(cachedData), &cachedOrder)
 		if err == nil {
 			s.logger.Infof("Cache hit for order %s", id)
+			if _, err := authorizeUser(ctx, cachedOrder.UserID); err != nil {
+				return nil, err
+			}
 			return &cachedOrder, nil
 		}
 		s.logger.Errorf("Failed to unmarshal cached order data: %v", err)
@@ -113,6 +187,10 @@ func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*models.Ord
 		return nil, err
 	}
 
+	if _, err := authorizeUser(ctx, order.UserID); err != nil {
+		return nil, err
+	}
+
 	jsonData, err := json.Marshal(order)
 	if err == nil {
 		err = s.cache.Set(cacheKey, string(jsonData), 10*time.Minute)
@@ -124,22 +202,75 @@ func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*models.Ord
 	return order, nil
 }
 
-func (s *OrderService) UpdateOrder(ctx context.Context, id string, status string) error {
-	if status != "pending" && status != "completed" && status != "cancelled" {
-		return errors.New("invalid status")
+// UpdateOrder moves an order to newStatus. Only the transitions declared in
+// models are allowed, and customers may only cancel their own orders; every
+// other change is made by admins or API keys. Cancelling returns the stock.
+func (s *OrderService) UpdateOrder(ctx context.Context, id string, newStatus string) error {
+	if !models.IsValidOrderStatus(newStatus) {
+		return status.Errorf(codes.InvalidArgument, "unknown order status %q", newStatus)
 	}
 
-	cacheKey := fmt.Sprintf("order:%s", id)
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := authorizeUser(ctx, order.UserID); err != nil {
+		return err
+	}
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if newStatus != models.OrderStatusCancelled && !principal.IsAdmin() && !principal.IsAPIKey() {
+		return status.Errorf(codes.PermissionDenied, "only admins can set order status to %s", newStatus)
+	}
+
+	if !models.CanTransitionOrder(order.Status, newStatus) {
+		return status.Errorf(codes.FailedPrecondition, "order %s cannot go from %s to %s", id, order.Status, newStatus)
+	}
+
+	updated, err := s.transitionOrder(ctx, order, newStatus, orderActor(ctx))
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return status.Errorf(codes.FailedPrecondition, "order %s was changed concurrently, reload it and retry", id)
+	}
+	return nil
+}
+
+// transitionOrder moves order to newStatus if nobody changed its status in
+// the meantime and reports whether it did. Cancelling or refunding an order
+// that still holds its stock returns it; only the caller that wins the
+// transition does so, so stock is never returned twice.
+func (s *OrderService) transitionOrder(ctx context.Context, order *models.Order, newStatus, actor string) (bool, error) {
+	change := models.OrderStatusChange{Status: newStatus, ChangedAt: time.Now(), Actor: actor}
+	updated, err := s.orderRepo.TransitionOrderStatus(ctx, order.OrderID, order.Status, change)
+	if err != nil || !updated {
+		return false, err
+	}
+
+	cacheKey := fmt.Sprintf("order:%s", order.OrderID)
 
 	if err := s.cache.Delete(cacheKey); err != nil {
-		s.logger.Infof("Failed to invalidate cache for order %s: %v", id, err)
+		s.logger.Infof("Failed to invalidate cache for order %s: %v", order.OrderID, err)
 	}
 
-	err := s.orderRepo.UpdateOrder(ctx, id, status)
-	return err
+	if (newStatus == models.OrderStatusCancelled || newStatus == models.OrderStatusRefunded) && order.HoldsStock() {
+		if err := s.productService.releaseDetached(order.Items); err != nil {
+			s.logger.Errorf("Failed to release stock of %s order %s: %v", newStatus, order.OrderID, err)
+		}
+	}
+
+	s.logger.Infof("Order %s moved from %s to %s by %s", order.OrderID, order.Status, newStatus, actor)
+	return true, nil
 }
 
 func (s *OrderService) GetOrdersByUserID(ctx context.Context, userID string) ([]*models.Order, error) {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	orders, err := s.orderRepo.GetOrdersByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -158,3 +289,15 @@ func (s *OrderService) CheckProductStock(ctx context.Context, productID string,
 		AvailableStock: availableStock,
 	}, nil
 }
+
+// orderActor names the caller for the status history of an order.
+func orderActor(ctx context.Context) string {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return models.OrderActorSystem
+	}
+	if principal.IsAPIKey() {
+		return "api_key:" + principal.APIKeyID
+	}
+	return "user:" + principal.UserID
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+	stdlogger "user-service/internal/infrastructure/logger"
+	"user-service/internal/infrastructure/utils/uuid"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unavailableProductRepo fails every product lookup.
+type unavailableProductRepo struct {
+	*fakeProductRepo
+	err error
+}
+
+func (r *unavailableProductRepo) GetProductByID(ctx context.Context, id string) (models.Product, error) {
+	return models.Product{}, r.err
+}
+
+func TestCreateOrder(t *testing.T) {
+	t.Run("Prices the items from the catalog", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 19.99, 5)
+		f.addProduct("p2", 5, 5)
+
+		order, err := f.service.CreateOrder(userContext("user-1"), "user-1", []OrderLine{
+			{ProductID: "p1", Quantity: 2},
+			{ProductID: "p2", Quantity: 1, ExpectedPrice: 5},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, models.MoneyFromFloat(44.98, "USD"), order.TotalPrice)
+		assert.Equal(t, "Product p1", order.Items[0].ProductName)
+		assert.Equal(t, models.MoneyFromFloat(19.99, "USD"), order.Items[0].PricePerUnit)
+		assert.Equal(t, 3, f.products.stock("p1"))
+		assert.Equal(t, 4, f.products.stock("p2"))
+	})
+
+	t.Run("Rejects a price the client did not see", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 19.99, 5)
+		f.addProduct("p2", 5, 5)
+
+		_, err := f.service.CreateOrder(userContext("user-1"), "user-1", []OrderLine{
+			{ProductID: "p1", Quantity: 2, ExpectedPrice: 19.99},
+			{ProductID: "p2", Quantity: 1, ExpectedPrice: 4.99},
+		})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "p2")
+
+		assert.Empty(t, f.orders.orders)
+		assert.Equal(t, 5, f.products.stock("p1"))
+		assert.Equal(t, 5, f.products.stock("p2"))
+	})
+
+	t.Run("Unknown product", func(t *testing.T) {
+		f := newOrderServiceFixture()
+
+		_, err := f.service.CreateOrder(userContext("user-1"), "user-1", []OrderLine{{ProductID: "missing", Quantity: 1}})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Catalog failures are not reported as unknown products", func(t *testing.T) {
+		failure := errors.New("connection reset")
+		products := &unavailableProductRepo{fakeProductRepo: newFakeProductRepo(), err: failure}
+		productService := NewProductService(products, &stdlogger.StdLogger{}, nil)
+		service := NewOrderService(newFakeOrderRepo(), NewPriceCalculator(), uuid.NewUUIDService(), productService, nil, &stdlogger.StdLogger{}, 30*time.Minute)
+
+		_, err := service.CreateOrder(userContext("user-1"), "user-1", []OrderLine{{ProductID: "p1", Quantity: 1}})
+		assert.ErrorIs(t, err, failure)
+		assert.NotEqual(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Rejects invalid quantities", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+
+		_, err := f.service.CreateOrder(userContext("user-1"), "user-1", []OrderLine{{ProductID: "p1", Quantity: 0}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = f.service.CreateOrder(userContext("user-1"), "user-1", nil)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
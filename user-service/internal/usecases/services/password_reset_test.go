@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"user-service/internal/infrastructure/utils/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPasswordReset(t *testing.T) {
+	t.Run("Unknown email is accepted silently", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+
+		assert.NoError(t, f.service.RequestPasswordReset(context.Background(), "nobody@example.com"))
+		assert.Equal(t, 0, f.email.count("password_reset"))
+	})
+
+	t.Run("Sets the new password and revokes existing sessions", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		session, err := f.jwt.GenerateTokenPair(jwt.NewIdentity(user))
+		require.NoError(t, err)
+
+		require.NoError(t, f.service.RequestPasswordReset(context.Background(), "alice@example.com"))
+		token := f.email.last("password_reset").token
+		require.NotEmpty(t, token)
+
+		require.NoError(t, f.service.ConfirmPasswordReset(context.Background(), token, "NewPassword456"))
+
+		_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "NewPassword456")
+		assert.NoError(t, err)
+		_, err = f.jwt.VerifyToken(session.AccessToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("Token is single-use", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		require.NoError(t, f.service.RequestPasswordReset(context.Background(), "alice@example.com"))
+		token := f.email.last("password_reset").token
+
+		require.NoError(t, f.service.ConfirmPasswordReset(context.Background(), token, "NewPassword456"))
+
+		err := f.service.ConfirmPasswordReset(context.Background(), token, "OtherPassword789")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Rejected password does not consume the token", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		require.NoError(t, f.service.RequestPasswordReset(context.Background(), "alice@example.com"))
+		token := f.email.last("password_reset").token
+
+		err := f.service.ConfirmPasswordReset(context.Background(), token, "short")
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		assert.NoError(t, f.service.ConfirmPasswordReset(context.Background(), token, "NewPassword456"))
+	})
+
+	t.Run("Concurrent confirmations succeed once", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		require.NoError(t, f.service.RequestPasswordReset(context.Background(), "alice@example.com"))
+		token := f.email.last("password_reset").token
+
+		const attempts = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- f.service.ConfirmPasswordReset(context.Background(), token, "NewPassword456")
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("Clears a forced reset", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		require.NoError(t, f.users.SetPasswordResetRequired(context.Background(), "user-1"))
+
+		_, err := f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		require.NoError(t, f.service.RequestPasswordReset(context.Background(), "alice@example.com"))
+		require.NoError(t, f.service.ConfirmPasswordReset(context.Background(), f.email.last("password_reset").token, "NewPassword456"))
+
+		_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "NewPassword456")
+		assert.NoError(t, err)
+	})
+}
@@ -1,6 +1,9 @@
 package services
 
-import "user-service/internal/core/models"
+import (
+	"fmt"
+	"user-service/internal/core/models"
+)
 
 type priceCalculator struct{}
 
@@ -8,10 +11,18 @@ func NewPriceCalculator() PriceCalculator {
 	return &priceCalculator{}
 }
 
-func (p *priceCalculator) CalculateTotalPrice(items []models.OrderItem) float64 {
-	var totalPrice float64
+func (p *priceCalculator) CalculateTotalPrice(items []models.OrderItem) (models.Money, error) {
+	if len(items) == 0 {
+		return models.Money{}, fmt.Errorf("cannot price an empty order")
+	}
+
+	totalPrice := models.Money{Currency: items[0].PricePerUnit.Currency}
 	for _, item := range items {
-		totalPrice += float64(item.Quantity) * item.PricePerUnit
+		var err error
+		totalPrice, err = totalPrice.Add(item.PricePerUnit.Multiply(item.Quantity))
+		if err != nil {
+			return models.Money{}, err
+		}
 	}
-	return totalPrice
+	return totalPrice, nil
 }
@@ -3,5 +3,7 @@ package services
 import "user-service/internal/core/models"
 
 type PriceCalculator interface {
-	CalculateTotalPrice(items []models.OrderItem) float64
+	// CalculateTotalPrice sums the items. All items must be priced in the same
+	// currency.
+	CalculateTotalPrice(items []models.OrderItem) (models.Money, error)
 }
@@ -5,7 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
-	"log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"time"
 	"user-service/internal/core/models"
 	"user-service/internal/errors"
@@ -15,6 +16,12 @@ import (
 	"user-service/internal/usecases/validators"
 )
 
+const (
+	stockReleaseTimeout    = 5 * time.Second
+	stockReleaseAttempts   = 3
+	stockReleaseRetryDelay = 100 * time.Millisecond
+)
+
 type ProductService struct {
 	productRepo repositories.ProductRepository
 	logger      logger.Logger
@@ -150,30 +157,114 @@ func (s *ProductService) CheckStock(ctx context.Context, productID string, quant
 	return inStock, int32(product.Stock), nil
 }
 
+// DecreaseStock takes quantity units of a product out of stock. The check and
+// the decrement are a single update, so concurrent calls cannot oversell.
 func (s *ProductService) DecreaseStock(ctx context.Context, productID string, quantity int32) (*models.Product, error) {
-
-	log.Printf("DecreaseStock called with ProductID=%s, Quantity=%d", productID, quantity)
-
 	if productID == "" || quantity <= 0 {
 		s.logger.Error("DecreaseStock: invalid request")
 		return nil, fmt.Errorf("invalid request")
 	}
 
-	product, err := s.productRepo.GetProductByID(ctx, productID)
+	reserved, err := s.productRepo.ReserveStock(ctx, productID, int(quantity))
 	if err != nil {
-		return nil, fmt.Errorf("product not found: %w", errors.ErrProductNotFound)
+		return nil, err
 	}
 
-	if product.Stock < int(quantity) {
+	product, err := s.productRepo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
 		s.logger.Error(fmt.Sprintf("DecreaseStock: insufficient stock for product %s", product.ID))
 		return nil, errors.ErrInsufficientStock
 	}
+	return &product, nil
+}
+
+// ReserveStock takes the stock for every item of an order. Either all items
+// are reserved or, when one of them fails, every reservation made so far is
+// released again.
+func (s *ProductService) ReserveStock(ctx context.Context, items []models.OrderItem) error {
+	for i, item := range items {
+		reserved, err := s.productRepo.ReserveStock(ctx, item.ProductID, item.Quantity)
+		if err == nil && !reserved {
+			err = s.insufficientStockError(ctx, item)
+		}
+		if err != nil {
+			s.releaseAfterFailure(items[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseStock puts the stock of every item back. It keeps going when an
+// item fails and returns the first error.
+func (s *ProductService) ReleaseStock(ctx context.Context, items []models.OrderItem) error {
+	_, err := s.releaseItems(ctx, items)
+	return err
+}
+
+// releaseItems releases every item and returns the ones that failed along
+// with the first error.
+func (s *ProductService) releaseItems(ctx context.Context, items []models.OrderItem) ([]models.OrderItem, error) {
+	var failed []models.OrderItem
+	var firstErr error
+	for _, item := range items {
+		if err := s.productRepo.ReleaseStock(ctx, item.ProductID, item.Quantity); err != nil {
+			s.logger.Errorf("Failed to release %d units of product %s: %v", item.Quantity, item.ProductID, err)
+			failed = append(failed, item)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return failed, firstErr
+}
+
+// releaseAfterFailure rolls back the reservations of a request that failed.
+func (s *ProductService) releaseAfterFailure(items []models.OrderItem) {
+	if err := s.releaseDetached(items); err != nil {
+		s.logger.Errorf("Failed to roll back stock reservations: %v", err)
+	}
+}
 
-	product.Stock -= int(quantity)
+// releaseDetached releases stock with its own context, so that stock taken or
+// freed by a request is still returned when the request is cancelled. Items
+// that fail are retried a few times, since their units are lost otherwise.
+func (s *ProductService) releaseDetached(items []models.OrderItem) error {
+	if len(items) == 0 {
+		return nil
+	}
 
-	updatedProduct, err := s.productRepo.UpdateProduct(ctx, product.ID, product)
+	ctx, cancel := context.WithTimeout(context.Background(), stockReleaseTimeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		failed, err := s.releaseItems(ctx, items)
+		if err == nil {
+			return nil
+		}
+		if attempt == stockReleaseAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * stockReleaseRetryDelay):
+		case <-ctx.Done():
+			return err
+		}
+		items = failed
+	}
+}
+
+func (s *ProductService) insufficientStockError(ctx context.Context, item models.OrderItem) error {
+	product, err := s.productRepo.GetProductByID(ctx, item.ProductID)
+	if err == errors.ErrProductNotFound {
+		return status.Errorf(codes.NotFound, "product %s not found", item.ProductID)
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &updatedProduct, nil
+	return status.Errorf(codes.FailedPrecondition, "product %s is out of stock (%d available)", item.ProductID, product.Stock)
 }
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"user-service/internal/core/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReserveStock(t *testing.T) {
+	items := []models.OrderItem{
+		{ProductID: "p1", Quantity: 2},
+		{ProductID: "p2", Quantity: 3},
+		{ProductID: "p3", Quantity: 1},
+	}
+
+	t.Run("Reserves every item", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+		f.addProduct("p2", 10, 5)
+		f.addProduct("p3", 10, 5)
+
+		require.NoError(t, f.service.productService.ReserveStock(context.Background(), items))
+
+		assert.Equal(t, 3, f.products.stock("p1"))
+		assert.Equal(t, 2, f.products.stock("p2"))
+		assert.Equal(t, 4, f.products.stock("p3"))
+	})
+
+	t.Run("Rolls back when an item is out of stock", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+		f.addProduct("p2", 10, 5)
+		f.addProduct("p3", 10, 0)
+
+		err := f.service.productService.ReserveStock(context.Background(), items)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		assert.Equal(t, 5, f.products.stock("p1"))
+		assert.Equal(t, 5, f.products.stock("p2"))
+		assert.Equal(t, 0, f.products.stock("p3"))
+	})
+
+	t.Run("Rolls back when the repository fails", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+		f.addProduct("p2", 10, 5)
+		f.addProduct("p3", 10, 5)
+		failure := errors.New("connection reset")
+		f.products.reserveErrors["p2"] = failure
+
+		err := f.service.productService.ReserveStock(context.Background(), items)
+		assert.ErrorIs(t, err, failure)
+
+		assert.Equal(t, 5, f.products.stock("p1"))
+		assert.Equal(t, 5, f.products.stock("p3"))
+	})
+
+	t.Run("Retries a failed release", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+		f.addProduct("p2", 10, 5)
+		f.addProduct("p3", 10, 0)
+		f.products.releaseFailures["p1"] = stockReleaseAttempts - 1
+
+		err := f.service.productService.ReserveStock(context.Background(), items)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		assert.Equal(t, 5, f.products.stock("p1"))
+		assert.Equal(t, 5, f.products.stock("p2"), "items that succeeded are released once")
+	})
+
+	t.Run("Gives up after the last attempt", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+		f.products.releaseFailures["p1"] = stockReleaseAttempts
+
+		err := f.service.productService.releaseDetached([]models.OrderItem{{ProductID: "p1", Quantity: 2}})
+		assert.Error(t, err)
+		assert.Equal(t, 5, f.products.stock("p1"))
+		assert.Zero(t, f.products.releaseFailures["p1"])
+	})
+
+	t.Run("Unknown product", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 5)
+
+		err := f.service.productService.ReserveStock(context.Background(), items[:2])
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, 5, f.products.stock("p1"))
+	})
+
+	t.Run("Concurrent reservations do not oversell", func(t *testing.T) {
+		f := newOrderServiceFixture()
+		f.addProduct("p1", 10, 10)
+		f.addProduct("p2", 10, 25)
+
+		var reserved atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// p2 runs out before p1 for some of these, which rolls back p1
+				err := f.service.productService.ReserveStock(context.Background(), []models.OrderItem{
+					{ProductID: "p1", Quantity: 1},
+					{ProductID: "p2", Quantity: 3},
+				})
+				if err == nil {
+					reserved.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(8), reserved.Load())
+		assert.Equal(t, 2, f.products.stock("p1"))
+		assert.Equal(t, 1, f.products.stock("p2"))
+	})
+}
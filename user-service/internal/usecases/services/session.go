@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sort"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/clientinfo"
+	jwt "user-service/internal/infrastructure/utils/jwt"
+)
+
+const sessionPrefix = "session:"
+
+func sessionKey(userID, sessionID string) string {
+	return sessionPrefix + userID + ":" + sessionID
+}
+
+// sessionSetKey names the set of session IDs of a user, which lets sessions be
+// listed without scanning the keyspace.
+func sessionSetKey(userID string) string {
+	return "sessions:" + userID
+}
+
+// IssueTokens logs a user in: it issues a token pair and records the new
+// session together with the client's address and user agent.
+func (u *UserService) IssueTokens(ctx context.Context, user models.User) (*jwt.TokenPair, error) {
+	tokens, err := u.jwtService.GenerateTokenPair(jwt.NewIdentity(user))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	u.saveSession(models.Session{
+		ID:         tokens.SessionID,
+		UserID:     user.ID,
+		TokenID:    tokens.TokenID,
+		UserAgent:  clientinfo.UserAgent(ctx),
+		IP:         clientinfo.IP(ctx),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  tokens.RefreshExpiresAt,
+	})
+
+	return tokens, nil
+}
+
+// touchSession records a token refresh, which is when a session is last seen.
+// Sessions started before sessions were tracked are recorded on first refresh.
+func (u *UserService) touchSession(ctx context.Context, userID string, tokens *jwt.TokenPair) {
+	now := time.Now()
+
+	session, ok := u.loadSession(userID, tokens.SessionID)
+	if !ok {
+		session = models.Session{ID: tokens.SessionID, UserID: userID, CreatedAt: now}
+	}
+
+	session.TokenID = tokens.TokenID
+	session.UserAgent = clientinfo.UserAgent(ctx)
+	session.IP = clientinfo.IP(ctx)
+	session.LastSeenAt = now
+	session.ExpiresAt = tokens.RefreshExpiresAt
+
+	u.saveSession(session)
+}
+
+// saveSession stores a session until its refresh token expires. Failures are
+// logged only: a missing record must not block a login.
+func (u *UserService) saveSession(session models.Session) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		u.logger.Errorf("Failed to encode session %s: %v", session.ID, err)
+		return
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	if err := u.cache.Set(sessionKey(session.UserID, session.ID), string(data), ttl); err != nil {
+		u.logger.Errorf("Failed to store session %s: %v", session.ID, err)
+		return
+	}
+	if err := u.cache.AddToSet(sessionSetKey(session.UserID), session.ID, ttl); err != nil {
+		u.logger.Errorf("Failed to index session %s: %v", session.ID, err)
+	}
+}
+
+// forgetSession removes the record of a session.
+func (u *UserService) forgetSession(userID, sessionID string) error {
+	if err := u.cache.Delete(sessionKey(userID, sessionID)); err != nil {
+		return err
+	}
+	return u.cache.RemoveFromSet(sessionSetKey(userID), sessionID)
+}
+
+func (u *UserService) loadSession(userID, sessionID string) (models.Session, bool) {
+	data, err := u.cache.Get(sessionKey(userID, sessionID))
+	if err != nil || data == "" {
+		return models.Session{}, false
+	}
+
+	var session models.Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		u.logger.Errorf("Failed to decode session %s: %v", sessionID, err)
+		return models.Session{}, false
+	}
+	return session, true
+}
+
+// ListSessions returns the active sessions of a user, most recently seen first.
+func (u *UserService) ListSessions(ctx context.Context, userID string) ([]models.Session, error) {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := u.cache.SetMembers(sessionSetKey(userID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list sessions: %v", err)
+	}
+
+	sessions := make([]models.Session, 0, len(ids))
+	for _, id := range ids {
+		session, ok := u.loadSession(userID, id)
+		if !ok {
+			// the session expired, which the set does not notice by itself
+			if err := u.cache.RemoveFromSet(sessionSetKey(userID), id); err != nil {
+				u.logger.Errorf("Failed to remove expired session %s: %v", id, err)
+			}
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession ends one session of a user. Its refresh token stops working
+// immediately and so do the access tokens issued for it.
+func (u *UserService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := u.loadSession(userID, sessionID); !ok {
+		return status.Errorf(codes.NotFound, "session %s not found", sessionID)
+	}
+
+	if err := u.jwtService.RevokeSession(sessionID); err != nil {
+		return err
+	}
+
+	if err := u.forgetSession(userID, sessionID); err != nil {
+		return err
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{
+		Action:   models.AuditSessionRevoked,
+		TargetID: userID,
+		Details:  map[string]string{"session_id": sessionID},
+	})
+	u.logger.Infof("Session %s of user %s revoked", sessionID, userID)
+	return nil
+}
+
+// revokeAllSessions invalidates every token of a user and forgets their
+// sessions.
+func (u *UserService) revokeAllSessions(userID string) error {
+	if err := u.jwtService.RevokeAllTokens(userID); err != nil {
+		return err
+	}
+
+	ids, err := u.cache.SetMembers(sessionSetKey(userID))
+	if err != nil {
+		u.logger.Errorf("Failed to list sessions of user %s: %v", userID, err)
+		return nil
+	}
+	for _, id := range ids {
+		if err := u.cache.Delete(sessionKey(userID, id)); err != nil {
+			u.logger.Errorf("Failed to remove session %s: %v", id, err)
+		}
+	}
+	if err := u.cache.Delete(sessionSetKey(userID)); err != nil {
+		u.logger.Errorf("Failed to remove sessions of user %s: %v", userID, err)
+	}
+	return nil
+}
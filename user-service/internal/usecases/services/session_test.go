@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+	apperrors "user-service/internal/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func clientContext(ip, userAgent string) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("user-agent", userAgent))
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 4242}})
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("Records and lists sessions", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		first, err := f.service.IssueTokens(clientContext("203.0.113.7", "curl/8.0"), user)
+		require.NoError(t, err)
+		second, err := f.service.IssueTokens(clientContext("198.51.100.2", "Mozilla/5.0"), user)
+		require.NoError(t, err)
+
+		sessions, err := f.service.ListSessions(userContext("user-1"), "user-1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		assert.Equal(t, second.SessionID, sessions[0].ID, "most recently seen first")
+		assert.Equal(t, "198.51.100.2", sessions[0].IP)
+		assert.Equal(t, "Mozilla/5.0", sessions[0].UserAgent)
+		assert.Equal(t, first.SessionID, sessions[1].ID)
+		assert.Equal(t, "203.0.113.7", sessions[1].IP)
+
+		ttl, err := f.cache.TTL(sessionSetKey("user-1"))
+		require.NoError(t, err)
+		assert.Greater(t, ttl, time.Until(first.RefreshExpiresAt)-time.Minute)
+	})
+
+	t.Run("Refresh updates the session", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		tokens, err := f.service.IssueTokens(clientContext("203.0.113.7", "curl/8.0"), user)
+		require.NoError(t, err)
+
+		refreshed, err := f.service.RefreshToken(clientContext("198.51.100.2", "curl/8.1"), tokens.RefreshToken)
+		require.NoError(t, err)
+
+		sessions, err := f.service.ListSessions(userContext("user-1"), "user-1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, tokens.SessionID, sessions[0].ID)
+		assert.Equal(t, refreshed.TokenID, sessions[0].TokenID)
+		assert.Equal(t, "198.51.100.2", sessions[0].IP)
+	})
+
+	t.Run("Leaves out expired sessions", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		expired, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+		active, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+
+		f.cache.Expire(sessionKey("user-1", expired.SessionID))
+
+		sessions, err := f.service.ListSessions(userContext("user-1"), "user-1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, active.SessionID, sessions[0].ID)
+
+		ids, err := f.cache.SetMembers(sessionSetKey("user-1"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{active.SessionID}, ids)
+	})
+
+	t.Run("Users only see their own sessions", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		tokens, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+
+		_, err = f.service.ListSessions(userContext("user-2"), "user-1")
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		err = f.service.RevokeSession(userContext("user-2"), "user-1", tokens.SessionID)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("Revokes a session", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		revoked, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+		kept, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+
+		require.NoError(t, f.service.RevokeSession(userContext("user-1"), "user-1", revoked.SessionID))
+
+		_, err = f.service.RefreshToken(context.Background(), revoked.RefreshToken)
+		assert.Error(t, err)
+		_, err = f.jwt.VerifyToken(revoked.AccessToken)
+		assert.Error(t, err)
+		_, err = f.jwt.VerifyToken(kept.AccessToken)
+		assert.NoError(t, err)
+
+		sessions, err := f.service.ListSessions(userContext("user-1"), "user-1")
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, kept.SessionID, sessions[0].ID)
+
+		err = f.service.RevokeSession(userContext("user-1"), "user-1", revoked.SessionID)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		f.log.Close()
+		events := f.audit.all()
+		require.NotEmpty(t, events)
+		assert.Equal(t, models.AuditSessionRevoked, events[len(events)-1].Action)
+	})
+
+	t.Run("Audits refresh token reuse", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		tokens, err := f.service.IssueTokens(context.Background(), user)
+		require.NoError(t, err)
+		_, err = f.service.RefreshToken(context.Background(), tokens.RefreshToken)
+		require.NoError(t, err)
+
+		_, err = f.service.RefreshToken(peerContext("203.0.113.7"), tokens.RefreshToken)
+		assert.ErrorIs(t, err, apperrors.ErrRefreshTokenReused)
+
+		f.log.Close()
+		events := f.audit.all()
+		require.NotEmpty(t, events)
+		event := events[len(events)-1]
+		assert.Equal(t, models.AuditRefreshTokenReused, event.Action)
+		assert.Equal(t, models.AuditActorUser, event.ActorType)
+		assert.Equal(t, "user-1", event.ActorID)
+		assert.Equal(t, "user-1", event.TargetID)
+		assert.Equal(t, "203.0.113.7", event.IP)
+		assert.Equal(t, models.AuditOutcomeFailure, event.Outcome)
+		assert.Equal(t, tokens.SessionID, event.Details["session_id"])
+	})
+
+	t.Run("Logging out of all sessions forgets them", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		for i := 0; i < 3; i++ {
+			_, err := f.service.IssueTokens(context.Background(), user)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, f.service.LogoutAllSessions(userContext("user-1"), "user-1"))
+
+		sessions, err := f.service.ListSessions(userContext("user-1"), "user-1")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+		exists, err := f.cache.Exists(sessionSetKey("user-1"))
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strconv"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/cache"
+	"user-service/internal/infrastructure/utils/security"
+)
+
+const (
+	totpIssuer = "ecommerce"
+
+	twoFactorChallengePrefix = "two_factor_challenge:"
+	twoFactorChallengeTTL    = 5 * time.Minute
+	twoFactorAttemptsPrefix  = "two_factor_attempts:"
+	twoFactorAttemptWindow   = 15 * time.Minute
+	maxTwoFactorAttempts     = 5
+	totpLastStepPrefix       = "totp_last_step:"
+	totpReplayWindow         = 3 * security.TOTPPeriod
+	maxTOTPStepUpdates       = 3
+	recoveryCodeCount        = 10
+)
+
+var errAdminTwoFactorRequired = status.Error(codes.FailedPrecondition, "administrators must enable two-factor authentication to sign in")
+
+// checkAdminTwoFactor keeps administrators from signing in with a single
+// factor. Admin rights are only granted to users with two-factor
+// authentication enabled and admins cannot disable it, so this only turns away
+// accounts that were made admins some other way.
+func checkAdminTwoFactor(user models.User) error {
+	if models.HasRole(user.Roles, models.RoleAdmin) && !user.TwoFactor.Enabled {
+		return errAdminTwoFactorRequired
+	}
+	return nil
+}
+
+// EnrollTwoFactor starts TOTP enrollment for the caller. Two-factor login is
+// not enabled until the first code is confirmed with ConfirmTwoFactor.
+func (u *UserService) EnrollTwoFactor(ctx context.Context) (secret, uri string, err error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	if user.TwoFactor.Enabled {
+		return "", "", status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+
+	secret, err = security.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := u.userRepo.UpdateTwoFactor(ctx, user.ID, models.TwoFactor{Secret: secret}); err != nil {
+		return "", "", err
+	}
+	u.invalidateProfileCache(user.ID)
+
+	return secret, security.TOTPProvisioningURI(totpIssuer, user.Email, secret), nil
+}
+
+// ConfirmTwoFactor enables two-factor login once the caller proves that their
+// authenticator app produces valid codes. It returns the recovery codes, which
+// are only ever shown this once.
+func (u *UserService) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactor.Enabled {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	}
+	if user.TwoFactor.Secret == "" {
+		return nil, status.Error(codes.FailedPrecondition, "two-factor enrollment has not been started")
+	}
+
+	if !u.verifyTOTPCode(user.ID, user.TwoFactor.Secret, code) {
+		return nil, status.Error(codes.InvalidArgument, "invalid verification code")
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := security.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		hashes = append(hashes, security.HashToken(security.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	twoFactor := models.TwoFactor{
+		Enabled:       true,
+		Secret:        user.TwoFactor.Secret,
+		RecoveryCodes: hashes,
+		EnabledAt:     time.Now(),
+	}
+	if err := u.userRepo.UpdateTwoFactor(ctx, user.ID, twoFactor); err != nil {
+		return nil, err
+	}
+	u.invalidateProfileCache(user.ID)
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditTwoFactorEnabled, TargetID: user.ID})
+	u.logger.Infof("Two-factor authentication enabled for user %s", user.ID)
+	return recoveryCodes, nil
+}
+
+// DisableTwoFactor turns two-factor login off. It requires a current TOTP code
+// or an unused recovery code.
+func (u *UserService) DisableTwoFactor(ctx context.Context, code string) error {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !user.TwoFactor.Enabled {
+		return status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	}
+	if models.HasRole(user.Roles, models.RoleAdmin) {
+		return status.Error(codes.FailedPrecondition, "administrators cannot disable two-factor authentication")
+	}
+
+	ok, err := u.verifySecondFactorCode(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return status.Error(codes.InvalidArgument, "invalid verification code")
+	}
+
+	if err := u.userRepo.UpdateTwoFactor(ctx, user.ID, models.TwoFactor{}); err != nil {
+		return err
+	}
+	u.invalidateProfileCache(user.ID)
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditTwoFactorDisabled, TargetID: user.ID})
+	u.logger.Infof("Two-factor authentication disabled for user %s", user.ID)
+	return nil
+}
+
+// CreateTwoFactorChallenge issues the short-lived token returned by the first
+// login step of a user with two-factor authentication enabled.
+func (u *UserService) CreateTwoFactorChallenge(user models.User) (string, error) {
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.cache.Set(twoFactorChallengePrefix+security.HashToken(token), user.ID, twoFactorChallengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// VerifySecondFactor completes a two-step login. Failed codes are counted per
+// user rather than per challenge, so that requesting new challenges does not
+// grant more guesses.
+func (u *UserService) VerifySecondFactor(ctx context.Context, challenge, code string) (models.User, error) {
+	key := twoFactorChallengePrefix + security.HashToken(challenge)
+
+	userID, err := u.cache.Get(key)
+	if err != nil || userID == "" {
+		return models.User{}, status.Error(codes.Unauthenticated, "invalid or expired login challenge")
+	}
+
+	attemptsKey := twoFactorAttemptsPrefix + userID
+	attempts, err := u.cache.IncrementWithTTL(attemptsKey, twoFactorAttemptWindow)
+	if err != nil {
+		u.logger.Errorf("Failed to count two-factor attempt: %v", err)
+	} else if attempts > maxTwoFactorAttempts {
+		_ = u.cache.Delete(key)
+		return models.User{}, status.Error(codes.ResourceExhausted, "too many invalid verification codes, try again later")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.User{}, status.Error(codes.Unauthenticated, "invalid or expired login challenge")
+	}
+
+	ok, err := u.verifySecondFactorCode(ctx, user, code)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !ok {
+		u.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditLogin,
+			TargetID: user.ID,
+			Outcome:  models.AuditOutcomeFailure,
+			Reason:   "invalid verification code",
+			Details:  map[string]string{"method": "two_factor"},
+		})
+		return models.User{}, status.Error(codes.Unauthenticated, "invalid verification code")
+	}
+
+	// a challenge completes a single login even if several valid codes for
+	// it arrive at once
+	if consumed, err := u.cache.GetAndDelete(key); err != nil || consumed != userID {
+		return models.User{}, status.Error(codes.Unauthenticated, "invalid or expired login challenge")
+	}
+	if err := u.cache.Delete(attemptsKey); err != nil {
+		u.logger.Errorf("Failed to clear two-factor attempts: %v", err)
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{
+		Action:   models.AuditLogin,
+		TargetID: user.ID,
+		Details:  map[string]string{"method": "two_factor"},
+	})
+	return user, nil
+}
+
+// verifySecondFactorCode accepts either a TOTP code or a recovery code. A
+// recovery code is consumed by a successful check.
+func (u *UserService) verifySecondFactorCode(ctx context.Context, user models.User, code string) (bool, error) {
+	if !user.TwoFactor.Enabled {
+		return false, nil
+	}
+
+	if len(code) == security.TOTPDigits {
+		return u.verifyTOTPCode(user.ID, user.TwoFactor.Secret, code), nil
+	}
+
+	hash := security.HashToken(security.NormalizeRecoveryCode(code))
+	consumed, err := u.userRepo.ConsumeRecoveryCode(ctx, user.ID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	if consumed {
+		u.invalidateProfileCache(user.ID)
+		u.logger.Infof("Recovery code used by user %s", user.ID)
+	}
+	return consumed, nil
+}
+
+// verifyTOTPCode checks a TOTP code and rejects codes from a time step that
+// was already used, so that an observed code cannot be replayed. The last used
+// step only moves forward through SetIfAbsent and CompareAndSwap, so of two
+// concurrent submissions of one code only one is accepted.
+func (u *UserService) verifyTOTPCode(userID, secret, code string) bool {
+	step, ok := security.VerifyTOTP(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	key := totpLastStepPrefix + userID
+	value := strconv.FormatInt(step, 10)
+	for attempt := 0; attempt < maxTOTPStepUpdates; attempt++ {
+		var recorded bool
+		last, err := u.cache.Get(key)
+		switch {
+		case err == cache.ErrCacheMiss:
+			recorded, err = u.cache.SetIfAbsent(key, value, totpReplayWindow)
+		case err == nil:
+			lastStep, parseErr := strconv.ParseInt(last, 10, 64)
+			if parseErr == nil && step <= lastStep {
+				return false
+			}
+			recorded, err = u.cache.CompareAndSwap(key, last, value, totpReplayWindow)
+		}
+		if err != nil {
+			u.logger.Errorf("Failed to record used TOTP step for user %s: %v", userID, err)
+			return false
+		}
+		if recorded {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *UserService) currentUser(ctx context.Context) (models.User, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return models.User{}, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if principal.IsAPIKey() {
+		return models.User{}, status.Error(codes.PermissionDenied, "this operation requires a user session")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, principal.UserID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if user.ID == "" {
+		return models.User{}, status.Errorf(codes.NotFound, "user with id %s not found", principal.UserID)
+	}
+	return user, nil
+}
+
+func (u *UserService) invalidateProfileCache(userID string) {
+	if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", userID)); err != nil {
+		u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", userID, err)
+	}
+}
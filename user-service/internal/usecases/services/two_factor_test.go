@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/security"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminTwoFactorPolicy(t *testing.T) {
+	t.Run("Admin without two-factor cannot log in with a password", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "admin-1", "admin@example.com", "Password123")
+		require.NoError(t, f.users.UpdateRoles(context.Background(), "admin-1", []string{models.RoleAdmin}))
+
+		_, err := f.service.AuthenticateUser(context.Background(), "admin@example.com", "Password123")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Admin with two-factor passes the first step", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "admin-1", "admin@example.com", "Password123")
+		require.NoError(t, f.users.UpdateRoles(context.Background(), "admin-1", []string{models.RoleAdmin}))
+		require.NoError(t, f.users.UpdateTwoFactor(context.Background(), "admin-1", models.TwoFactor{Enabled: true, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: time.Now()}))
+
+		user, err := f.service.AuthenticateUser(context.Background(), "admin@example.com", "Password123")
+		require.NoError(t, err)
+		assert.True(t, user.TwoFactor.Enabled)
+	})
+
+	t.Run("Admin role requires two-factor", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+		ctx := userContext("admin-1", models.RoleAdmin)
+
+		err := f.service.SetUserRoles(ctx, "user-1", []string{models.RoleAdmin})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t, []string{models.RoleCustomer}, f.users.get("user-1").Roles)
+
+		require.NoError(t, f.users.UpdateTwoFactor(context.Background(), "user-1", models.TwoFactor{Enabled: true, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: time.Now()}))
+		assert.NoError(t, f.service.SetUserRoles(ctx, "user-1", []string{models.RoleAdmin}))
+	})
+
+	t.Run("Admin cannot disable two-factor", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "admin-1", "admin@example.com", "Password123")
+		require.NoError(t, f.users.UpdateRoles(context.Background(), "admin-1", []string{models.RoleAdmin}))
+		require.NoError(t, f.users.UpdateTwoFactor(context.Background(), "admin-1", models.TwoFactor{Enabled: true, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: time.Now()}))
+
+		err := f.service.DisableTwoFactor(userContext("admin-1", models.RoleAdmin), "123456")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.True(t, f.users.get("admin-1").TwoFactor.Enabled)
+	})
+}
+
+func TestTOTPReplay(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+
+	t.Run("A code is accepted once", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		code, err := security.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+
+		assert.True(t, f.service.verifyTOTPCode("user-1", secret, code))
+		assert.False(t, f.service.verifyTOTPCode("user-1", secret, code))
+	})
+
+	t.Run("An older code is rejected after a newer one", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		older, err := security.GenerateTOTPCode(secret, time.Now().Add(-security.TOTPPeriod))
+		require.NoError(t, err)
+		current, err := security.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+
+		assert.True(t, f.service.verifyTOTPCode("user-1", secret, current))
+		assert.False(t, f.service.verifyTOTPCode("user-1", secret, older))
+	})
+
+	t.Run("Concurrent submissions of one code", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		code, err := security.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+
+		var accepted atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if f.service.verifyTOTPCode("user-1", secret, code) {
+					accepted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), accepted.Load())
+	})
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	"user-service/internal/errors"
+	"user-service/internal/interfaces/repositories"
+)
+
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+var errAccountDisabled = status.Error(codes.PermissionDenied, "this account has been disabled")
+
+var assignableRoles = []string{models.RoleCustomer, models.RoleAdmin}
+
+// authorizeAdmin lets admins and API keys through. The gRPC policy already
+// enforces this; the check keeps the service safe when called directly.
+func authorizeAdmin(ctx context.Context) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, errors.ErrUnauthenticated.Error())
+	}
+	// API keys are not tied to a person and cannot act as administrators
+	if principal.IsAPIKey() || !principal.IsAdmin() {
+		return nil, status.Error(codes.PermissionDenied, "admin role required")
+	}
+	return principal, nil
+}
+
+// SearchUsers returns one page of users matching filter and the total number
+// of matches. Pages are numbered from 1.
+func (u *UserService) SearchUsers(ctx context.Context, filter repositories.UserFilter, page, pageSize int) ([]models.User, int64, error) {
+	if _, err := authorizeAdmin(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultUserPageSize
+	}
+	if pageSize > maxUserPageSize {
+		pageSize = maxUserPageSize
+	}
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return nil, 0, status.Error(codes.InvalidArgument, "created_after must be before created_before")
+	}
+
+	filter.Email = strings.TrimSpace(filter.Email)
+	filter.Username = strings.TrimSpace(filter.Username)
+
+	users, total, err := u.userRepo.SearchUsers(ctx, filter, int64((page-1)*pageSize), int64(pageSize))
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "failed to search users: %v", err)
+	}
+	return users, total, nil
+}
+
+// SetUserDisabled disables or re-enables an account. Disabling logs the user
+// out everywhere.
+func (u *UserService) SetUserDisabled(ctx context.Context, userID string, disabled bool) error {
+	principal, err := authorizeAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if disabled && userID == principal.UserID {
+		return status.Error(codes.FailedPrecondition, "you cannot disable your own account")
+	}
+
+	if _, err := u.adminTarget(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.SetDisabled(ctx, userID, disabled); err != nil {
+		return err
+	}
+	u.invalidateProfileCache(userID)
+
+	if disabled {
+		if err := u.revokeAllSessions(userID); err != nil {
+			return err
+		}
+	}
+
+	action := models.AuditAccountEnabled
+	if disabled {
+		action = models.AuditAccountDisabled
+	}
+	u.audit.Record(ctx, models.AuditEvent{Action: action, TargetID: userID})
+	u.logger.Infof("User %s disabled=%t by %s", userID, disabled, principalName(principal))
+	return nil
+}
+
+// ForcePasswordReset invalidates the current password of a user, logs them
+// out everywhere and emails them a reset link.
+func (u *UserService) ForcePasswordReset(ctx context.Context, userID string) error {
+	principal, err := authorizeAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.adminTarget(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.SetPasswordResetRequired(ctx, userID); err != nil {
+		return err
+	}
+	u.invalidateProfileCache(userID)
+
+	if err := u.revokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	if err := u.sendPasswordResetEmail(user); err != nil {
+		u.logger.Errorf("Failed to send password reset email to user %s: %v", userID, err)
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditPasswordResetForced, TargetID: userID})
+	u.logger.Infof("Password reset forced for user %s by %s", userID, principalName(principal))
+	return nil
+}
+
+// SetUserRoles replaces the roles of a user. Roles are part of the access
+// token, so existing sessions are revoked for the change to take effect.
+func (u *UserService) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	principal, err := authorizeAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	roles, err = normalizeRoles(roles)
+	if err != nil {
+		return err
+	}
+	if userID == principal.UserID && !models.HasRole(roles, models.RoleAdmin) {
+		return status.Error(codes.FailedPrecondition, "you cannot remove your own admin role")
+	}
+
+	target, err := u.adminTarget(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if models.HasRole(roles, models.RoleAdmin) && !target.TwoFactor.Enabled {
+		return status.Error(codes.FailedPrecondition, "the user must enable two-factor authentication before becoming an administrator")
+	}
+
+	if err := u.userRepo.UpdateRoles(ctx, userID, roles); err != nil {
+		return err
+	}
+	u.invalidateProfileCache(userID)
+
+	if err := u.revokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{
+		Action:   models.AuditRolesChanged,
+		TargetID: userID,
+		Details:  map[string]string{"roles": strings.Join(roles, ",")},
+	})
+	u.logger.Infof("Roles of user %s set to %v by %s", userID, roles, principalName(principal))
+	return nil
+}
+
+func (u *UserService) adminTarget(ctx context.Context, userID string) (models.User, error) {
+	if userID == "" {
+		return models.User{}, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user.ID == "" || user.IsDeleted() {
+		return models.User{}, status.Errorf(codes.NotFound, "user with id %s not found", userID)
+	}
+	return user, nil
+}
+
+func normalizeRoles(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one role is required")
+	}
+
+	normalized := make([]string, 0, len(roles))
+	for _, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !models.HasRole(assignableRoles, role) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown role %q", role)
+		}
+		if !models.HasRole(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+	return normalized, nil
+}
+
+func principalName(principal *auth.Principal) string {
+	return "admin " + principal.UserID
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"user-service/internal/core/auth"
+	"user-service/internal/core/models"
+	"user-service/internal/infrastructure/utils/jwt"
+	"user-service/internal/interfaces/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func adminContext() context.Context {
+	return userContext("admin-1", models.RoleAdmin)
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	f := newUserServiceFixture(t)
+	f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+	apiKey := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Type:     auth.PrincipalAPIKey,
+		APIKeyID: "key-1",
+		Scopes:   []string{"/user.UserService/SearchUsers", "/user.UserService/DisableUser"},
+	})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{name: "Anonymous", ctx: context.Background(), code: codes.Unauthenticated},
+		{name: "Customer", ctx: userContext("user-2"), code: codes.PermissionDenied},
+		{name: "API key", ctx: apiKey, code: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := f.service.SearchUsers(tt.ctx, repositories.UserFilter{}, 1, 10)
+			assert.Equal(t, tt.code, status.Code(err))
+
+			err = f.service.SetUserDisabled(tt.ctx, "user-1", true)
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.False(t, f.users.get("user-1").Disabled)
+
+			err = f.service.ForcePasswordReset(tt.ctx, "user-1")
+			assert.Equal(t, tt.code, status.Code(err))
+
+			err = f.service.SetUserRoles(tt.ctx, "user-1", []string{models.RoleCustomer})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	f := newUserServiceFixture(t)
+	for i := 0; i < 5; i++ {
+		f.addUser(t, fmt.Sprintf("user-%d", i), fmt.Sprintf("alice%d@example.com", i), "Password123")
+	}
+	f.addUser(t, "user-9", "bob@example.com", "Password123")
+
+	users, total, err := f.service.SearchUsers(adminContext(), repositories.UserFilter{Email: " Alice"}, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, users, 2)
+	assert.Equal(t, "user-2", users[0].ID)
+
+	now := time.Now()
+	_, _, err = f.service.SearchUsers(adminContext(), repositories.UserFilter{CreatedAfter: now, CreatedBefore: now.Add(-time.Hour)}, 1, 10)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSetUserDisabled(t *testing.T) {
+	t.Run("Disables and logs the user out", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		session, err := f.jwt.GenerateTokenPair(jwt.NewIdentity(user))
+		require.NoError(t, err)
+
+		require.NoError(t, f.service.SetUserDisabled(adminContext(), "user-1", true))
+
+		_, err = f.jwt.VerifyToken(session.AccessToken)
+		assert.Error(t, err)
+		_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		require.NoError(t, f.service.SetUserDisabled(adminContext(), "user-1", false))
+		_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Admins cannot disable themselves", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "admin-1", "admin@example.com", "Password123")
+
+		err := f.service.SetUserDisabled(adminContext(), "admin-1", true)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+
+		err := f.service.SetUserDisabled(adminContext(), "missing", true)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestForcePasswordReset(t *testing.T) {
+	f := newUserServiceFixture(t)
+	f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+	require.NoError(t, f.service.ForcePasswordReset(adminContext(), "user-1"))
+
+	_, err := f.service.AuthenticateUser(context.Background(), "alice@example.com", "Password123")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	reset := f.email.last("password_reset")
+	assert.Equal(t, "alice@example.com", reset.to)
+	require.NoError(t, f.service.ConfirmPasswordReset(context.Background(), reset.token, "NewPassword456"))
+	_, err = f.service.AuthenticateUser(context.Background(), "alice@example.com", "NewPassword456")
+	assert.NoError(t, err)
+}
+
+func TestSetUserRoles(t *testing.T) {
+	t.Run("Replaces the roles and logs the user out", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		user := f.addUser(t, "user-1", "alice@example.com", "Password123")
+		session, err := f.jwt.GenerateTokenPair(jwt.NewIdentity(user))
+		require.NoError(t, err)
+
+		require.NoError(t, f.users.UpdateTwoFactor(context.Background(), "user-1", models.TwoFactor{Enabled: true, Secret: "JBSWY3DPEHPK3PXP"}))
+		require.NoError(t, f.service.SetUserRoles(adminContext(), "user-1", []string{" Admin ", "customer", "admin"}))
+
+		assert.Equal(t, []string{models.RoleAdmin, models.RoleCustomer}, f.users.get("user-1").Roles)
+		_, err = f.jwt.VerifyToken(session.AccessToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects unknown roles", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "user-1", "alice@example.com", "Password123")
+
+		err := f.service.SetUserRoles(adminContext(), "user-1", []string{"superuser"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		err = f.service.SetUserRoles(adminContext(), "user-1", nil)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Admins cannot demote themselves", func(t *testing.T) {
+		f := newUserServiceFixture(t)
+		f.addUser(t, "admin-1", "admin@example.com", "Password123")
+
+		err := f.service.SetUserRoles(adminContext(), "admin-1", []string{models.RoleCustomer})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
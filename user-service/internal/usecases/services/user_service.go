@@ -5,13 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"strings"
+	"sync"
 	"time"
+	"user-service/internal/core/auth"
 	"user-service/internal/core/models"
+	apperrors "user-service/internal/errors"
 	"user-service/internal/infrastructure/cache"
+	"user-service/internal/infrastructure/utils/clientinfo"
 	jwt "user-service/internal/infrastructure/utils/jwt"
 	"user-service/internal/infrastructure/utils/security"
 	"user-service/internal/infrastructure/utils/uuid"
@@ -21,33 +24,61 @@ import (
 	"user-service/internal/usecases/validators"
 )
 
+const (
+	passwordResetPrefix = "password_reset:"
+	passwordResetTTL    = 30 * time.Minute
+
+	emailVerificationPrefix         = "email_verification:"
+	emailVerificationUserPrefix     = "email_verification_user:"
+	emailVerificationThrottlePrefix = "email_verification_throttle:"
+	emailVerificationTTL            = 24 * time.Hour
+	emailVerificationResendInterval = time.Minute
+)
+
+// emailVerification is what an email verification token stands for: the
+// address it was sent to, which is the only one it can verify.
+type emailVerification struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
 type UserService struct {
 	userRepo      repositories.UserRepository
 	userValidator validators.UserValidator
 	passwordHash  security.PasswordHash
 	jwtService    jwt.JWTService
 	uuidGenerator uuid.Generator
-	client        *mongo.Client
 	orderRepo     repositories.OrderRepository
 	cache         cache.CacheService
 	logger        logger.Logger
 	email         services.EmailService
+	verification  auth.EmailVerificationPolicy
+	// deletionGracePeriod is how long a deleted account can be restored.
+	deletionGracePeriod time.Duration
+	audit               *AuditLog
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
 func NewUserService(userRepo repositories.UserRepository, userValidator validators.UserValidator,
-	hash security.PasswordHash, jwtService jwt.JWTService, uuidGenerator uuid.Generator, client *mongo.Client, orderRepo repositories.OrderRepository,
-	cache cache.CacheService, logger logger.Logger, email services.EmailService) *UserService {
+	hash security.PasswordHash, jwtService jwt.JWTService, uuidGenerator uuid.Generator, orderRepo repositories.OrderRepository,
+	cache cache.CacheService, logger logger.Logger, email services.EmailService, verification auth.EmailVerificationPolicy,
+	deletionGracePeriod time.Duration, audit *AuditLog) *UserService {
 	return &UserService{
 		userRepo:      userRepo,
 		userValidator: userValidator,
 		passwordHash:  hash,
 		jwtService:    jwtService,
 		uuidGenerator: uuidGenerator,
-		client:        client,
 		orderRepo:     orderRepo,
 		cache:         cache,
 		logger:        logger,
 		email:         email,
+		verification:  verification,
+
+		deletionGracePeriod: deletionGracePeriod,
+		audit:               audit,
 	}
 }
 
@@ -55,15 +86,17 @@ func (u *UserService) RegisterUser(ctx context.Context, user models.User) (model
 
 	err := u.userValidator.Validate(user)
 	if err != nil {
-		return models.User{}, err
+		return models.User{}, invalidArgument(err)
 	}
 
+	// concurrent registrations can pass these checks together, the unique
+	// indexes then reject all but one of them in CreateUser
 	existingUser, err := u.userRepo.GetUserByEmail(ctx, user.Email)
 	if err != nil {
 		return models.User{}, err
 	}
 	if existingUser.ID != "" {
-		return models.User{}, errors.New("user with this email already exists")
+		return models.User{}, &apperrors.AlreadyExistsError{Field: "email"}
 	}
 
 	existingUsername, err := u.userRepo.GetUserByUsername(ctx, user.Username)
@@ -71,7 +104,7 @@ func (u *UserService) RegisterUser(ctx context.Context, user models.User) (model
 		return models.User{}, err
 	}
 	if existingUsername.ID != "" {
-		return models.User{}, errors.New("user with this username already exists")
+		return models.User{}, &apperrors.AlreadyExistsError{Field: "username"}
 	}
 
 	hashedPassword, err := u.passwordHash.HashPassword(user.Password)
@@ -81,29 +114,286 @@ func (u *UserService) RegisterUser(ctx context.Context, user models.User) (model
 	user.Password = hashedPassword
 
 	user.ID = u.uuidGenerator.GenerateUUID()
+	user.Roles = []string{models.RoleCustomer}
+	user.Status = models.UserStatusUnverified
 	createdUser, err := u.userRepo.CreateUser(ctx, user)
 	if err != nil {
 		return models.User{}, err
 	}
 
-	_ = u.email.SendWelcomeEmail(user.Email)
+	if err := u.sendVerificationEmail(createdUser); err != nil {
+		u.logger.Errorf("Failed to send verification email to user %s: %v", createdUser.ID, err)
+	}
 
 	return createdUser, nil
 }
 
+func (u *UserService) VerifyEmail(ctx context.Context, token string) error {
+	key := emailVerificationPrefix + security.HashToken(token)
+
+	data, err := u.cache.GetAndDelete(key)
+	if err != nil || data == "" {
+		return status.Error(codes.InvalidArgument, "invalid or expired verification token")
+	}
+
+	var verification emailVerification
+	if err := json.Unmarshal([]byte(data), &verification); err != nil || verification.UserID == "" {
+		return status.Error(codes.InvalidArgument, "invalid or expired verification token")
+	}
+	userID := verification.UserID
+
+	if err := u.cache.Delete(emailVerificationUserPrefix + userID); err != nil {
+		u.logger.Errorf("Failed to remove verification token of user %s: %v", userID, err)
+	}
+
+	// the address may have changed since the token was sent; the token must
+	// not vouch for the new one
+	verified, err := u.userRepo.MarkEmailVerified(ctx, userID, verification.Email)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return status.Error(codes.InvalidArgument, "invalid or expired verification token")
+	}
+
+	if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", userID)); err != nil {
+		u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", userID, err)
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err == nil {
+		if err := u.email.SendWelcomeEmail(user.Email); err != nil {
+			u.logger.Errorf("Failed to send welcome email to user %s: %v", userID, err)
+		}
+	}
+
+	u.logger.Infof("Email verified for user %s", userID)
+	return nil
+}
+
+// ResendVerificationEmail sends a fresh verification link at most once per
+// resend interval for an address. Unknown and already verified addresses are
+// accepted silently.
+func (u *UserService) ResendVerificationEmail(ctx context.Context, email string) error {
+	throttleKey := emailVerificationThrottlePrefix + strings.ToLower(email)
+
+	if throttled, err := u.cache.Exists(throttleKey); err == nil && throttled {
+		return status.Error(codes.ResourceExhausted, "a verification email was sent recently, please try again later")
+	}
+
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == "" || user.IsDeleted() || user.IsEmailVerified() {
+		return u.cache.Set(throttleKey, "1", emailVerificationResendInterval)
+	}
+
+	if err := u.sendVerificationEmail(user); err != nil {
+		u.logger.Errorf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// sendVerificationEmail sends a verification link for the current address of
+// user. It replaces the token sent before, so only the latest link works.
+func (u *UserService) sendVerificationEmail(user models.User) error {
+	if err := u.revokeVerificationToken(user.ID); err != nil {
+		return err
+	}
+
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(emailVerification{UserID: user.ID, Email: user.Email})
+	if err != nil {
+		return err
+	}
+
+	tokenKey := emailVerificationPrefix + security.HashToken(token)
+	if err := u.cache.Set(tokenKey, string(data), emailVerificationTTL); err != nil {
+		return err
+	}
+	if err := u.cache.Set(emailVerificationUserPrefix+user.ID, tokenKey, emailVerificationTTL); err != nil {
+		return err
+	}
+
+	if err := u.cache.Set(emailVerificationThrottlePrefix+strings.ToLower(user.Email), "1", emailVerificationResendInterval); err != nil {
+		return err
+	}
+
+	return u.email.SendVerificationEmail(user.Email, token, emailVerificationTTL)
+}
+
+// revokeVerificationToken deletes the outstanding verification token of a
+// user, if any.
+func (u *UserService) revokeVerificationToken(userID string) error {
+	tokenKey, err := u.cache.GetAndDelete(emailVerificationUserPrefix + userID)
+	if err == cache.ErrCacheMiss {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return u.cache.Delete(tokenKey)
+}
+
+// AuthenticateUser checks the credentials of a login attempt. Unknown emails and
+// wrong passwords produce the same error, and repeated failures lock the
+// account and the client address for an exponentially growing period.
 func (u *UserService) AuthenticateUser(ctx context.Context, email, password string) (models.User, error) {
+	account := loginAccountKey(email)
+	ip := clientinfo.IP(ctx)
 
-	user, err := u.userRepo.AuthenticateUser(ctx, email, password)
+	if err := u.checkLoginLock(account, ip); err != nil {
+		return models.User{}, err
+	}
+
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
 		return models.User{}, err
 	}
 
+	if user.ID == "" {
+		// compare against a dummy hash so that unknown emails take as long as known ones
+		u.passwordHash.CheckPasswordHash(password, u.getDummyHash())
+		u.recordLoginFailure(ctx, account, ip, user)
+		u.auditLoginFailure(ctx, "", email, "unknown email")
+		return models.User{}, status.Error(codes.Unauthenticated, apperrors.ErrInvalidCredentials.Error())
+	}
+
 	if !u.passwordHash.CheckPasswordHash(password, user.Password) {
-		return models.User{}, errors.New("incorrect password")
+		u.recordLoginFailure(ctx, account, ip, user)
+		u.auditLoginFailure(ctx, user.ID, email, "wrong password")
+		return models.User{}, status.Error(codes.Unauthenticated, apperrors.ErrInvalidCredentials.Error())
+	}
+
+	u.clearLoginFailures(account)
+
+	if user.IsDeleted() {
+		u.auditLoginFailure(ctx, user.ID, email, "account deleted")
+		return models.User{}, errAccountDeleted
+	}
+	if user.Disabled {
+		u.auditLoginFailure(ctx, user.ID, email, "account disabled")
+		return models.User{}, errAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		u.auditLoginFailure(ctx, user.ID, email, "password reset required")
+		return models.User{}, status.Error(codes.FailedPrecondition, "a password reset is required, use the link sent by email to set a new password")
+	}
+
+	u.rehashPassword(ctx, user, password)
+
+	if u.verification == auth.EmailVerificationForLogin && !user.IsEmailVerified() {
+		u.auditLoginFailure(ctx, user.ID, email, "email not verified")
+		return models.User{}, status.Error(codes.FailedPrecondition, "email address must be verified before logging in")
+	}
+
+	if err := checkAdminTwoFactor(user); err != nil {
+		u.auditLoginFailure(ctx, user.ID, email, "admin without two-factor authentication")
+		return models.User{}, err
+	}
+
+	// with two-factor authentication the login completes in VerifySecondFactor
+	if !user.TwoFactor.Enabled {
+		u.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditLogin,
+			TargetID: user.ID,
+			Details:  map[string]string{"method": "password"},
+		})
 	}
 	return user, nil
 }
 
+func (u *UserService) auditLoginFailure(ctx context.Context, userID, email, reason string) {
+	u.audit.Record(ctx, models.AuditEvent{
+		Action:   models.AuditLogin,
+		TargetID: userID,
+		Outcome:  models.AuditOutcomeFailure,
+		Reason:   reason,
+		Details:  map[string]string{"method": "password", "email": email},
+	})
+}
+
+// rehashPassword upgrades a stored hash that uses an outdated algorithm or
+// parameters. Failures are only logged since the login itself succeeded.
+func (u *UserService) rehashPassword(ctx context.Context, user models.User, password string) {
+	if !u.passwordHash.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := u.passwordHash.HashPassword(password)
+	if err != nil {
+		u.logger.Errorf("Failed to rehash password for user %s: %v", user.ID, err)
+		return
+	}
+
+	if err := u.userRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		u.logger.Errorf("Failed to store rehashed password for user %s: %v", user.ID, err)
+		return
+	}
+
+	u.invalidateProfileCache(user.ID)
+	u.logger.Infof("Password hash upgraded for user %s", user.ID)
+}
+
+func (u *UserService) getDummyHash() string {
+	u.dummyHashOnce.Do(func() {
+		hash, err := u.passwordHash.HashPassword("dummy-password")
+		if err != nil {
+			u.logger.Errorf("Failed to prepare dummy password hash: %v", err)
+			return
+		}
+		u.dummyHash = hash
+	})
+	return u.dummyHash
+}
+
+func (u *UserService) RefreshToken(ctx context.Context, refreshToken string) (*jwt.TokenPair, error) {
+	var sessionUserID string
+
+	tokens, err := u.jwtService.RefreshToken(refreshToken, func(userID string) (jwt.Identity, error) {
+		user, err := u.userRepo.GetUserByID(ctx, userID)
+		if err != nil || user.IsDeleted() {
+			return jwt.Identity{}, status.Error(codes.Unauthenticated, "user no longer exists")
+		}
+		if user.Disabled {
+			return jwt.Identity{}, errAccountDisabled
+		}
+		if err := checkAdminTwoFactor(user); err != nil {
+			return jwt.Identity{}, err
+		}
+
+		if u.verification == auth.EmailVerificationForLogin && !user.IsEmailVerified() {
+			return jwt.Identity{}, status.Error(codes.FailedPrecondition, "email address must be verified before logging in")
+		}
+
+		sessionUserID = userID
+		return jwt.NewIdentity(user), nil
+	})
+	var reused *apperrors.RefreshTokenReuseError
+	if errors.As(err, &reused) {
+		u.logger.Errorf("Refresh token reuse detected for user %s, session %s revoked", reused.UserID, reused.SessionID)
+		u.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditRefreshTokenReused,
+			TargetID: reused.UserID,
+			Outcome:  models.AuditOutcomeFailure,
+			Reason:   "refresh token reused, session revoked",
+			Details:  map[string]string{"session_id": reused.SessionID},
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.touchSession(ctx, sessionUserID, tokens)
+	return tokens, nil
+}
+
 func (u *UserService) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
 	cacheKey := fmt.Sprintf("user_profile:%s", userID)
 
@@ -124,7 +414,7 @@ func (u *UserService) GetUserByID(ctx context.Context, userID string) (*models.U
 		return &user, err
 	}
 
-	if user.ID == "" {
+	if user.ID == "" || user.IsDeleted() {
 		return &user, errors.New("user not found")
 	}
 
@@ -136,47 +426,237 @@ func (u *UserService) GetUserByID(ctx context.Context, userID string) (*models.U
 	return &user, nil
 }
 
-func (s *UserService) DeleteUserAndOrders(ctx context.Context, usedID string, tokenString string) error {
-	session, err := s.client.StartSession()
-
+func (u *UserService) GetProfile(ctx context.Context, userID string) (*models.User, error) {
+	userID, err := authorizeUser(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer session.EndSession(ctx)
+	return u.GetUserByID(ctx, userID)
+}
 
-	if strings.TrimSpace(usedID) == "" {
-		return status.Errorf(codes.InvalidArgument, "user_id is required")
+// UpdateProfile changes the username and/or email of a user. Empty fields are
+// left unchanged. A new email address has to be verified again.
+func (u *UserService) UpdateProfile(ctx context.Context, userID, username, email string) (*models.User, error) {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := s.GetUserByID(ctx, usedID)
+	user, err := u.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		return status.Errorf(codes.Internal, "failed to check user existence: %v", err)
+		return nil, err
+	}
+	if user.ID == "" {
+		return nil, status.Errorf(codes.NotFound, "user with id %s not found", userID)
 	}
 
-	if user == nil {
-		return status.Errorf(codes.NotFound, "user with id %s not found", usedID)
+	updated := user
+	if username = strings.TrimSpace(username); username != "" {
+		updated.Username = username
+	}
+	if email = strings.TrimSpace(email); email != "" {
+		updated.Email = email
 	}
 
-	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
-		if err := s.orderRepo.DeleteOrdersByUserID(sessCtx, usedID); err != nil {
+	if err := u.userValidator.ValidateProfile(updated); err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	emailChanged := !strings.EqualFold(updated.Email, user.Email)
+	if emailChanged {
+		existingUser, err := u.userRepo.GetUserByEmail(ctx, updated.Email)
+		if err != nil {
 			return nil, err
 		}
+		if existingUser.ID != "" && existingUser.ID != userID {
+			return nil, &apperrors.AlreadyExistsError{Field: "email"}
+		}
+		updated.Status = models.UserStatusUnverified
+	}
 
-		if err := s.userRepo.DeleteUser(sessCtx, usedID); err != nil {
+	if updated.Username != user.Username {
+		existingUsername, err := u.userRepo.GetUserByUsername(ctx, updated.Username)
+		if err != nil {
 			return nil, err
 		}
+		if existingUsername.ID != "" && existingUsername.ID != userID {
+			return nil, &apperrors.AlreadyExistsError{Field: "username"}
+		}
+	}
 
-		if err := s.jwtService.InvalidateToken(tokenString); err != nil {
-			return nil, err
+	updated.UpdatedAt = time.Now()
+	if err := u.userRepo.UpdateProfile(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", userID)); err != nil {
+		u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", userID, err)
+	}
+
+	if emailChanged {
+		// this also revokes the link sent to the old address
+		if err := u.sendVerificationEmail(updated); err != nil {
+			u.logger.Errorf("Failed to send verification email to user %s: %v", userID, err)
 		}
-		return nil, nil
 	}
 
-	_, err = session.WithTransaction(ctx, callback)
+	u.logger.Infof("Profile updated for user %s", userID)
+	return &updated, nil
+}
+
+// ChangePassword replaces the caller's password after checking the current
+// one. Every existing session is revoked; the caller receives a new one from
+// the transport layer.
+func (u *UserService) ChangePassword(ctx context.Context, currentPassword, newPassword string) (*models.User, error) {
+	user, err := u.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.passwordHash.CheckPasswordHash(currentPassword, user.Password) {
+		return nil, status.Error(codes.InvalidArgument, "current password is incorrect")
+	}
+
+	if err := u.userValidator.ValidatePassword(newPassword); err != nil {
+		return nil, invalidArgument(err)
+	}
 
+	hashedPassword, err := u.passwordHash.HashPassword(newPassword)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", user.ID)); err != nil {
+		u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", user.ID, err)
+	}
+
+	if err := u.revokeAllSessions(user.ID); err != nil {
+		return nil, err
+	}
+
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditPasswordChanged, TargetID: user.ID})
+	u.logger.Infof("Password changed for user %s", user.ID)
+	return &user, nil
+}
+
+func (u *UserService) Logout(ctx context.Context, tokenString string) error {
+	if err := u.jwtService.InvalidateToken(tokenString); err != nil {
+		return status.Errorf(codes.Unauthenticated, "failed to invalidate token: %v", err)
+	}
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if principal.SessionID != "" {
+		if err := u.jwtService.RevokeSession(principal.SessionID); err != nil {
+			return err
+		}
+		if err := u.forgetSession(principal.UserID, principal.SessionID); err != nil {
+			u.logger.Errorf("Failed to remove session %s: %v", principal.SessionID, err)
+		}
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{
+		Action:   models.AuditLogout,
+		TargetID: principal.UserID,
+		Details:  map[string]string{"session_id": principal.SessionID},
+	})
+	return nil
+}
+
+func (u *UserService) LogoutAllSessions(ctx context.Context, userID string) error {
+	userID, err := authorizeUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.revokeAllSessions(userID); err != nil {
+		return err
+	}
+
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditLogoutAllSessions, TargetID: userID})
+	u.logger.Infof("All sessions revoked for user %s", userID)
+	return nil
+}
+
+// RequestPasswordReset emails a single-use reset token. It reports success for
+// unknown addresses too, so that it cannot be used to probe for accounts.
+func (u *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := u.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == "" || user.IsDeleted() {
+		u.logger.Info("Password reset requested for an unknown email")
+		return nil
+	}
+
+	if err := u.sendPasswordResetEmail(user); err != nil {
+		u.logger.Errorf("Failed to send password reset email to user %s: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+func (u *UserService) sendPasswordResetEmail(user models.User) error {
+	token, err := security.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := u.cache.Set(passwordResetPrefix+security.HashToken(token), user.ID, passwordResetTTL); err != nil {
+		return err
+	}
+
+	return u.email.SendPasswordResetEmail(user.Email, token, passwordResetTTL)
+}
+
+func (u *UserService) ConfirmPasswordReset(ctx context.Context, token, newPassword string) error {
+	key := passwordResetPrefix + security.HashToken(token)
+
+	userID, err := u.cache.Get(key)
+	if err != nil || userID == "" {
+		return status.Error(codes.InvalidArgument, "invalid or expired reset token")
+	}
+
+	if err := u.userValidator.ValidatePassword(newPassword); err != nil {
+		return invalidArgument(err)
+	}
+
+	// the token is only consumed once the new password is known to be
+	// acceptable; of several concurrent confirmations only one consumes it
+	if consumed, err := u.cache.GetAndDelete(key); err != nil || consumed != userID {
+		return status.Error(codes.InvalidArgument, "invalid or expired reset token")
+	}
+
+	hashedPassword, err := u.passwordHash.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+
+	if err := u.cache.Delete(fmt.Sprintf("user_profile:%s", userID)); err != nil {
+		u.logger.Errorf("Failed to invalidate profile cache for user %s: %v", userID, err)
+	}
+
+	if err := u.revokeAllSessions(userID); err != nil {
 		return err
 	}
 
+	u.audit.Record(ctx, models.AuditEvent{Action: models.AuditPasswordReset, TargetID: userID})
+	u.logger.Infof("Password reset completed for user %s", userID)
 	return nil
 }
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"user-service/internal/usecases/validators"
+)
+
+// invalidArgument converts a validation failure into an InvalidArgument status.
+// Per-rule violations are attached as a BadRequest detail so that clients can
+// map them to form fields.
+func invalidArgument(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+
+	verr, ok := err.(*validators.ValidationError)
+	if !ok {
+		return st.Err()
+	}
+
+	details := &errdetails.BadRequest{}
+	for _, v := range verr.Violations {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Message,
+			Reason:      strings.ToUpper(v.Rule),
+		})
+	}
+
+	withDetails, detailsErr := st.WithDetails(details)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -1,14 +1,12 @@
 package validators
 
-import "errors"
+import (
+	"errors"
+	"user-service/internal/core/models"
+)
 
 func IsValidOrderStatus(status string) bool {
-	switch status {
-	case "pending", "completed", "cancelled":
-		return true
-	default:
-		return false
-	}
+	return models.IsValidOrderStatus(status)
 }
 
 func ValidateOrderStatus(status string) error {
@@ -0,0 +1,121 @@
+package validators
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DefaultAllowedSymbols are the printable ASCII symbols and the space, so that
+// passphrases are accepted.
+const DefaultAllowedSymbols = " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSymbol  bool
+	AllowedSymbols string
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      128,
+		RequireUpper:   true,
+		RequireDigit:   true,
+		AllowedSymbols: DefaultAllowedSymbols,
+	}
+}
+
+// BreachedPasswords is a set of known leaked passwords, compared
+// case-insensitively.
+type BreachedPasswords map[string]struct{}
+
+// LoadBreachedPasswords reads a list with one password per line. An empty path
+// yields an empty list.
+func LoadBreachedPasswords(path string) (BreachedPasswords, error) {
+	breached := BreachedPasswords{}
+	if path == "" {
+		return breached, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return breached, nil
+}
+
+func (b BreachedPasswords) Contains(password string) bool {
+	_, ok := b[strings.ToLower(password)]
+	return ok
+}
+
+func (p PasswordPolicy) validate(password string, breached BreachedPasswords) error {
+	verr := &ValidationError{}
+	length := len([]rune(password))
+
+	if length < p.MinLength {
+		verr.add("password", "min_length", fmt.Sprintf("password must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		verr.add("password", "max_length", fmt.Sprintf("password must be at most %d characters long", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	var disallowed []rune
+	for _, ch := range password {
+		switch {
+		case unicode.IsUpper(ch):
+			hasUpper = true
+		case unicode.IsLower(ch):
+			hasLower = true
+		case unicode.IsDigit(ch):
+			hasDigit = true
+		case strings.ContainsRune(p.AllowedSymbols, ch):
+			hasSymbol = true
+		case unicode.IsLetter(ch):
+		default:
+			disallowed = append(disallowed, ch)
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		verr.add("password", "uppercase", "password must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		verr.add("password", "lowercase", "password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		verr.add("password", "digit", "password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		verr.add("password", "symbol", "password must contain a symbol")
+	}
+	if len(disallowed) > 0 {
+		verr.add("password", "allowed_symbols", fmt.Sprintf("password contains characters that are not allowed: %q", string(disallowed)))
+	}
+
+	if breached.Contains(password) {
+		verr.add("password", "breached", "password appears in a list of breached passwords, please choose another one")
+	}
+
+	return verr.errOrNil()
+}
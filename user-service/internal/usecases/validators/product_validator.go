@@ -10,9 +10,12 @@ func ValidateProductForCreation(product models.Product) error {
 	if product.Name == "" {
 		return errors.ErrMissingName
 	}
-	if product.Price <= 0 {
+	if !product.Price.IsPositive() {
 		return errors.ErrInvalidPrice
 	}
+	if !models.IsValidCurrency(product.Price.Currency) {
+		return errors.ErrInvalidCurrency
+	}
 	if product.Stock <= 0 {
 		return errors.ErrInvalidStock
 	}
@@ -29,9 +32,12 @@ func ValidateProductForUpdate(product models.Product) error {
 	if product.Name == "" {
 		return errors.ErrMissingName
 	}
-	if product.Price <= 0 {
+	if !product.Price.IsPositive() {
 		return errors.ErrInvalidPrice
 	}
+	if !models.IsValidCurrency(product.Price.Currency) {
+		return errors.ErrInvalidCurrency
+	}
 	if product.Stock < 0 {
 		return errors.ErrInvalidStock
 	}
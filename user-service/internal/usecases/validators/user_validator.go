@@ -1,36 +1,55 @@
 package validators
 
 import (
-	"errors"
 	"regexp"
-	"unicode"
 	"user-service/internal/core/models"
 )
 
-type userValidator struct{}
+type userValidator struct {
+	passwordPolicy    PasswordPolicy
+	breachedPasswords BreachedPasswords
+}
 
-func NewUserValidator() UserValidator {
-	return &userValidator{}
+func NewUserValidator(passwordPolicy PasswordPolicy, breachedPasswords BreachedPasswords) UserValidator {
+	return &userValidator{
+		passwordPolicy:    passwordPolicy,
+		breachedPasswords: breachedPasswords,
+	}
 }
 
 func (v *userValidator) Validate(user models.User) error {
-	if user.Username == "" || user.Email == "" || user.Password == "" {
-		return errors.New("all fields must be filled")
-	}
+	verr := &ValidationError{}
+	verr.merge(v.ValidateProfile(user))
 
-	if !isValidEmail(user.Email) {
-		return errors.New("invalid email format")
+	if user.Password == "" {
+		verr.add("password", "required", "password is required")
+	} else {
+		verr.merge(v.ValidatePassword(user.Password))
 	}
 
-	if !isValidUsername(user.Username) {
-		return errors.New("username must only contain Latin letters and digits, no special characters")
+	return verr.errOrNil()
+}
+
+func (v *userValidator) ValidateProfile(user models.User) error {
+	verr := &ValidationError{}
+
+	if user.Username == "" {
+		verr.add("username", "required", "username is required")
+	} else if !isValidUsername(user.Username) {
+		verr.add("username", "format", "username must only contain Latin letters and digits, no special characters")
 	}
 
-	if !isValidPassword(user.Password) {
-		return errors.New("password must be at least 6 characters long, contain Latin letters, digits, and at least one uppercase letter")
+	if user.Email == "" {
+		verr.add("email", "required", "email is required")
+	} else if !isValidEmail(user.Email) {
+		verr.add("email", "format", "invalid email format")
 	}
 
-	return nil
+	return verr.errOrNil()
+}
+
+func (v *userValidator) ValidatePassword(password string) error {
+	return v.passwordPolicy.validate(password, v.breachedPasswords)
 }
 
 func isValidEmail(email string) bool {
@@ -44,24 +63,3 @@ func isValidUsername(username string) bool {
 	re := regexp.MustCompile(usernameRegex)
 	return re.MatchString(username)
 }
-
-func isValidPassword(password string) bool {
-	if len(password) < 6 {
-		return false
-	}
-
-	var hasDigit, hasUpper bool
-	for _, ch := range password {
-		if unicode.IsDigit(ch) {
-			hasDigit = true
-		}
-		if unicode.IsUpper(ch) {
-			hasUpper = true
-		}
-		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
-			return false
-		}
-	}
-
-	return hasDigit && hasUpper
-}
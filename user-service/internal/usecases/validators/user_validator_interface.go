@@ -4,4 +4,6 @@ import "user-service/internal/core/models"
 
 type UserValidator interface {
 	Validate(user models.User) error
+	ValidateProfile(user models.User) error
+	ValidatePassword(password string) error
 }
@@ -0,0 +1,77 @@
+package validators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"user-service/internal/core/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rules(err error) []string {
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		return nil
+	}
+
+	var result []string
+	for _, v := range verr.Violations {
+		result = append(result, v.Rule)
+	}
+	return result
+}
+
+func TestValidatePassword(t *testing.T) {
+	validator := NewUserValidator(DefaultPasswordPolicy(), BreachedPasswords{"password123": {}})
+
+	t.Run("Accepts passphrases with symbols", func(t *testing.T) {
+		assert.NoError(t, validator.ValidatePassword("Correct horse battery 9!"))
+	})
+
+	t.Run("Reports every failed rule", func(t *testing.T) {
+		err := validator.ValidatePassword("abc")
+		assert.ElementsMatch(t, []string{"min_length", "uppercase", "digit"}, rules(err))
+	})
+
+	t.Run("Rejects characters outside the allowed symbols", func(t *testing.T) {
+		err := validator.ValidatePassword("Secret123\t")
+		assert.Equal(t, []string{"allowed_symbols"}, rules(err))
+	})
+
+	t.Run("Rejects breached passwords case-insensitively", func(t *testing.T) {
+		err := validator.ValidatePassword("Password123")
+		assert.Equal(t, []string{"breached"}, rules(err))
+	})
+
+	t.Run("Applies custom policy", func(t *testing.T) {
+		strict := NewUserValidator(PasswordPolicy{MinLength: 12, RequireSymbol: true, AllowedSymbols: "!"}, nil)
+
+		err := strict.ValidatePassword("longenough12?")
+		assert.ElementsMatch(t, []string{"symbol", "allowed_symbols"}, rules(err))
+		assert.NoError(t, strict.ValidatePassword("longenough12!"))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	validator := NewUserValidator(DefaultPasswordPolicy(), nil)
+
+	err := validator.Validate(models.User{Username: "bad name", Email: "not-an-email"})
+	assert.ElementsMatch(t, []string{"format", "format", "required"}, rules(err))
+
+	assert.NoError(t, validator.Validate(models.User{Username: "salem", Email: "salem@example.com", Password: "Password123"}))
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("123456\nQwerty123\n\n"), 0600))
+
+	breached, err := LoadBreachedPasswords(path)
+	assert.NoError(t, err)
+	assert.Len(t, breached, 2)
+	assert.True(t, breached.Contains("qwerty123"))
+
+	empty, err := LoadBreachedPasswords("")
+	assert.NoError(t, err)
+	assert.False(t, empty.Contains("123456"))
+}
@@ -0,0 +1,42 @@
+package validators
+
+import "strings"
+
+// Violation describes a single failed validation rule.
+type Violation struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// ValidationError collects every rule a value failed, so that clients can show
+// all problems at once instead of one per attempt.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(field, rule, message string) {
+	e.Violations = append(e.Violations, Violation{Field: field, Rule: rule, Message: message})
+}
+
+func (e *ValidationError) merge(err error) {
+	if other, ok := err.(*ValidationError); ok {
+		e.Violations = append(e.Violations, other.Violations...)
+	}
+}
+
+// errOrNil avoids returning a typed nil pointer as a non-nil error.
+func (e *ValidationError) errOrNil() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}